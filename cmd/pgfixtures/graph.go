@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var graphFormat string
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Render the dependency graph of the fixture tables",
+		RunE:  func(cmd *cobra.Command, args []string) error { return runGraph(cmd.Context()) },
+	}
+
+	addConnectionFlags(cmd)
+	cmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format (dot or mermaid)")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runGraph(ctx context.Context) error {
+	if graphFormat != "dot" && graphFormat != "mermaid" {
+		return fmt.Errorf("unsupported format: %s (supported formats: dot, mermaid)", graphFormat)
+	}
+
+	l, err := newLoader()
+	if err != nil {
+		return err
+	}
+	defer l.DB.Close()
+
+	graph, err := l.Graph(ctx)
+	if err != nil {
+		return fmt.Errorf("read dependency graph: %w", err)
+	}
+
+	if graphFormat == "mermaid" {
+		return graph.WriteMermaid(os.Stdout)
+	}
+
+	return graph.WriteDOT(os.Stdout)
+}
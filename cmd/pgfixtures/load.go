@@ -6,22 +6,40 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql" // MySQL driver
 	_ "github.com/lib/pq"              // PostgreSQL driver
 	"github.com/spf13/cobra"
 
 	"github.com/rom8726/pgfixtures"
+	"github.com/rom8726/pgfixtures/internal/db"
 	"github.com/rom8726/pgfixtures/internal/loader"
 )
 
 var (
-	file     string
-	connStr  string
-	dbType   string
-	truncate bool
-	resetSeq bool
-	dryRun   bool
+	file            string
+	connStr         string
+	dbType          string
+	truncate        bool
+	resetSeq        bool
+	dryRun          bool
+	nowStr          string
+	seed            int64
+	checkSchema     bool
+	schemaAlias     map[string]string
+	fastTruncate    bool
+	strictGenerated bool
+	workers         int
+	txMode          string
+	isolation       string
+	stmtTimeout     time.Duration
+	lockTimeout     time.Duration
+	retryAttempts   int
+	waitTimeout     time.Duration
+	retryBackoff    time.Duration
+	lockName        string
+	lockWait        time.Duration
 )
 
 func init() {
@@ -31,18 +49,111 @@ func init() {
 		RunE:  func(cmd *cobra.Command, args []string) error { return runLoad(cmd.Context()) },
 	}
 
+	addLoaderFlags(cmd)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print actions without executing")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Seed for $fake/$uuid generators (0 picks a random seed)")
+	cmd.Flags().StringVar(&nowStr, "now", "", "Freeze the clock for $now/$today directives (RFC3339)")
+	cmd.Flags().IntVar(&workers, "workers", 1, "Load independent tables over this many connections (one transaction per level and worker)")
+	cmd.Flags().StringVar(&txMode, "tx", string(loader.TxSingle), "Transactions of the load: single, per-table or none")
+	cmd.Flags().StringVar(&isolation, "isolation", "", "Isolation level: read-committed, repeatable-read or serializable (default: the server's)")
+	cmd.Flags().DurationVar(&stmtTimeout, "statement-timeout", 0, "Fail statements running longer than this, e.g. 30s (0: no limit)")
+	cmd.Flags().DurationVar(&lockTimeout, "lock-timeout", 0, "Fail statements waiting longer than this for a lock, e.g. 10s (0: no limit)")
+	cmd.Flags().IntVar(&retryAttempts, "retry-attempts", 1, "Try a load failing with a serialization failure or deadlock up to this many times")
+	cmd.Flags().DurationVar(&waitTimeout, "wait", 0, "Wait up to this long for the database to accept connections, e.g. 30s")
+	cmd.Flags().DurationVar(&retryBackoff, "retry-backoff", 0, "Pause after the first failed attempt, doubled for each next one up to 5s (default 100ms)")
+	cmd.Flags().StringVar(&lockName, "lock", "", "Take this named lock around the load, so concurrent loads into one database wait for each other")
+	cmd.Flags().DurationVar(&lockWait, "lock-wait", 0, "Fail if the --lock lock isn't acquired within this time, e.g. 2m (0: wait forever)")
+
+	rootCmd.AddCommand(cmd)
+}
+
+// addConnectionFlags registers the flags shared by the commands that read fixtures against a database
+func addConnectionFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&file, "file", "f", "fixtures.yml", "Path to YAML fixture file")
 	cmd.Flags().StringVar(&connStr, "db", "", "Database connection string (required)")
 	cmd.Flags().StringVar(&dbType, "db-type", "postgres", "Database type (postgres or mysql)")
-	cmd.Flags().BoolVar(&truncate, "truncate", true, "Truncate tables before loading")
-	cmd.Flags().BoolVar(&resetSeq, "reset-seq", true, "Reset sequences after loading")
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print actions without executing")
+	cmd.Flags().StringToStringVar(&schemaAlias, "schema-alias", nil, "Map a fixture schema to a MySQL database, e.g. public=app_test (repeatable)")
 
 	_ = cmd.MarkFlagRequired("db")
-	rootCmd.AddCommand(cmd)
+}
+
+// addLoaderFlags registers the connection flags and the flags that change how fixtures are loaded
+func addLoaderFlags(cmd *cobra.Command) {
+	addConnectionFlags(cmd)
+	cmd.Flags().BoolVar(&truncate, "truncate", true, "Truncate tables before loading")
+	cmd.Flags().BoolVar(&resetSeq, "reset-seq", true, "Reset sequences after loading")
+	cmd.Flags().BoolVar(&checkSchema, "check-schema", true, "Validate fixtures against the database schema before loading")
+	cmd.Flags().BoolVar(&strictGenerated, "strict-generated", false, "Reject values for generated columns instead of dropping them")
+	cmd.Flags().BoolVar(&fastTruncate, "fast-truncate", false, "MySQL: clean tables with TRUNCATE instead of DELETE (faster, not rolled back on failure)")
 }
 
 func runLoad(ctx context.Context) error {
+	var now func() time.Time
+	if nowStr != "" {
+		frozen, err := time.Parse(time.RFC3339, nowStr)
+		if err != nil {
+			return fmt.Errorf("parse --now: %w", err)
+		}
+		now = func() time.Time { return frozen }
+	}
+
+	level, err := parseIsolation(isolation)
+	if err != nil {
+		return err
+	}
+
+	l, err := newLoader()
+	if err != nil {
+		return err
+	}
+	defer l.DB.Close()
+
+	l.Config.DryRun = dryRun
+	l.Config.Now = now
+	l.Config.Seed = seed
+	l.Config.Workers = workers
+	l.Config.Tx = loader.TxConfig{
+		Mode:             loader.TxMode(txMode),
+		Isolation:        level,
+		StatementTimeout: stmtTimeout,
+		LockTimeout:      lockTimeout,
+	}
+	l.Config.Retry = loader.RetryConfig{
+		Attempts:    retryAttempts,
+		WaitTimeout: waitTimeout,
+		Backoff:     retryBackoff,
+	}
+	l.Config.Lock = loader.LockConfig{Name: lockName, Wait: lockWait}
+
+	if err := l.Load(ctx); err != nil {
+		return fmt.Errorf("load fixtures: %w", err)
+	}
+
+	log.Println("Fixtures loaded successfully")
+
+	return nil
+}
+
+// parseIsolation converts the --isolation flag, "" keeps the server default
+func parseIsolation(name string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return sql.LevelDefault, nil
+	case "read-uncommitted":
+		return sql.LevelReadUncommitted, nil
+	case "read-committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable-read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return 0, fmt.Errorf("unsupported isolation level: %s (supported levels: read-uncommitted, read-committed, repeatable-read, serializable)", name)
+	}
+}
+
+// newLoader opens the database from the shared flags, the caller closes l.DB
+func newLoader() (*loader.Loader, error) {
 	// Convert string database type to DatabaseType
 	var databaseType pgfixtures.DatabaseType
 	switch strings.ToLower(dbType) {
@@ -51,13 +162,19 @@ func runLoad(ctx context.Context) error {
 	case "mysql":
 		databaseType = pgfixtures.MySQL
 	default:
-		return fmt.Errorf("unsupported database type: %s (supported types: postgres, mysql)", dbType)
+		return nil, fmt.Errorf("unsupported database type: %s (supported types: postgres, mysql)", dbType)
 	}
 
 	// Create database implementation
 	database, err := pgfixtures.NewDatabase(databaseType)
 	if err != nil {
-		return fmt.Errorf("create database implementation: %w", err)
+		return nil, fmt.Errorf("create database implementation: %w", err)
+	}
+	if mysqlDB, ok := database.(*db.MySQLDatabase); ok {
+		if len(schemaAlias) > 0 {
+			mysqlDB.SchemaAliases = schemaAlias
+		}
+		mysqlDB.UseTruncate = fastTruncate
 	}
 
 	// Get the appropriate database driver name
@@ -72,27 +189,18 @@ func runLoad(ctx context.Context) error {
 	// Open database connection
 	sqlDB, err := sql.Open(driverName, connStr)
 	if err != nil {
-		return fmt.Errorf("connect to DB: %w", err)
+		return nil, fmt.Errorf("connect to DB: %w", err)
 	}
-	defer sqlDB.Close()
 
-	// Create and run loader
-	l := loader.Loader{
+	return &loader.Loader{
 		DB:       sqlDB,
 		Database: database,
 		Config: loader.LoaderConfig{
-			FilePath: file,
-			Truncate: truncate,
-			ResetSeq: resetSeq,
-			DryRun:   dryRun,
+			FilePath:        file,
+			Truncate:        truncate,
+			ResetSeq:        resetSeq,
+			CheckSchema:     checkSchema,
+			StrictGenerated: strictGenerated,
 		},
-	}
-
-	if err := l.Load(ctx); err != nil {
-		return fmt.Errorf("load fixtures: %w", err)
-	}
-
-	log.Println("Fixtures loaded successfully")
-
-	return nil
+	}, nil
 }
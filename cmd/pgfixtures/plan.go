@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var planFormat string
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the load order, cleanup and sequence resets without touching any data",
+		RunE:  func(cmd *cobra.Command, args []string) error { return runPlan(cmd.Context()) },
+	}
+
+	addLoaderFlags(cmd)
+	cmd.Flags().StringVar(&planFormat, "format", "text", "Output format (text or json)")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runPlan(ctx context.Context) error {
+	if planFormat != "text" && planFormat != "json" {
+		return fmt.Errorf("unsupported format: %s (supported formats: text, json)", planFormat)
+	}
+
+	l, err := newLoader()
+	if err != nil {
+		return err
+	}
+	defer l.DB.Close()
+
+	plan, err := l.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("plan fixtures: %w", err)
+	}
+
+	if planFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(plan)
+	}
+
+	return plan.WriteText(os.Stdout)
+}
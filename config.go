@@ -2,6 +2,9 @@ package pgfixtures
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/rom8726/pgfixtures/internal/loader"
 )
 
 // DatabaseType represents the type of database
@@ -14,6 +17,27 @@ const (
 	MySQL DatabaseType = "mysql"
 )
 
+// TxMode selects how a load is split into transactions
+type TxMode = loader.TxMode
+
+const (
+	// TxSingle loads everything in one transaction (default)
+	TxSingle = loader.TxSingle
+	// TxPerTable commits the cleanup, every table and the sequence updates separately
+	TxPerTable = loader.TxPerTable
+	// TxNone runs without transactions, every statement commits on its own
+	TxNone = loader.TxNone
+)
+
+// TxConfig holds the transaction mode, the isolation level and the statement and lock timeouts
+type TxConfig = loader.TxConfig
+
+// RetryConfig holds how long to wait for the database and how often to retry a load
+type RetryConfig = loader.RetryConfig
+
+// LockConfig holds the name of the lock serializing loads and how long to wait for it
+type LockConfig = loader.LockConfig
+
 type Config struct {
 	FilePath     string
 	ConnStr      string
@@ -21,6 +45,45 @@ type Config struct {
 	Truncate     bool
 	ResetSeq     bool
 	DryRun       bool
+	// Now returns the reference time for $now, $today and $time directives.
+	// Set it to a fixed clock to make time-dependent fixtures reproducible (default: time.Now).
+	Now func() time.Time
+	// Seed makes $fake(...) and $uuid values reproducible across runs (0 picks a random seed)
+	Seed int64
+	// Every row is validated against the live catalog before any table is cleaned: unknown tables
+	// and columns, missing required columns and values for generated columns are reported together
+	// in one error. SkipSchemaCheck turns that off, like --check-schema=false in the CLI.
+	SkipSchemaCheck bool
+	// StrictGenerated rejects values for generated columns instead of dropping them with a log line
+	StrictGenerated bool
+	// SchemaAliases maps schema names used in fixtures to MySQL databases ("" is the current one).
+	// Unless overridden here, "public" refers to the current database. Ignored for PostgreSQL.
+	SchemaAliases map[string]string
+	// FastTruncate makes MySQL clean tables with TRUNCATE TABLE instead of DELETE. TRUNCATE commits
+	// implicitly, so a failed load leaves the tables empty instead of rolling back. Ignored for PostgreSQL,
+	// where TRUNCATE is transactional.
+	FastTruncate bool
+	// Workers above 1 loads tables that don't depend on each other concurrently, over that many
+	// connections. Each dependency level is committed before the next one starts, so a failure can't
+	// be rolled back; with Truncate the tables are cleaned again instead. Custom directives must be
+	// safe for concurrent use.
+	Workers int
+	// Tx selects one transaction for the whole load (default), one per table or none, for loads too
+	// big for one transaction. Without a single transaction a failure is handled like with Workers.
+	// StatementTimeout and LockTimeout (SET LOCAL on PostgreSQL) make a blocked TRUNCATE fail
+	// instead of hanging.
+	Tx TxConfig
+	// Retry waits up to WaitTimeout for the database to answer before the load, and tries a load
+	// failing with a serialization failure or a deadlock up to Attempts times, with exponential
+	// backoff. Loads that commit more than once, including MySQL loads that update auto-increment
+	// counters or use FastTruncate, are only retried with Truncate, which starts every attempt
+	// from empty tables.
+	Retry RetryConfig
+	// Lock takes a named lock around the whole load (pg_advisory_lock on PostgreSQL, GET_LOCK
+	// on MySQL), so concurrent loads into one database wait for each other instead of interleaving.
+	// The lock is held on a connection of its own, which needs one more open connection. Dry runs
+	// skip it.
+	Lock LockConfig
 }
 
 func (c *Config) Validate() error {
@@ -34,6 +97,9 @@ func (c *Config) Validate() error {
 		// Default to PostgreSQL for backward compatibility
 		c.DatabaseType = PostgreSQL
 	}
+	if c.Workers < 0 {
+		return fmt.Errorf("workers must not be negative")
+	}
 
 	return nil
 }
@@ -0,0 +1,31 @@
+package pgfixtures
+
+import (
+	"fmt"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/directive"
+	"github.com/rom8726/pgfixtures/internal/loader"
+	"github.com/rom8726/pgfixtures/internal/parser"
+)
+
+// Func computes the value of a custom directive such as `$bcrypt(secret)`
+type Func = directive.Func
+
+// FuncArgs describes a directive call: its argument, the row being built and the transaction
+type FuncArgs = directive.Args
+
+// Querier is the subset of *sql.Tx available to directives
+type Querier = db.Querier
+
+// RegisterFunc registers a custom value directive available as `$name` and `$name(arg)`
+// in every fixture loaded afterwards. Registering a name twice replaces the previous func.
+// It panics if the name is invalid or belongs to a built-in directive
+// (eval, now, today, time, fake, uuid, file, base64, hex, json).
+func RegisterFunc(name string, fn Func) {
+	if loader.IsBuiltin(name) || parser.IsStaticDirective(name) {
+		panic(fmt.Sprintf("pgfixtures: %q is a built-in directive", name))
+	}
+
+	directive.Default.Register(name, fn)
+}
@@ -0,0 +1,31 @@
+package pgfixtures
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/directive"
+)
+
+func TestRegisterFunc(t *testing.T) {
+	RegisterFunc("tenant_test", func(ctx context.Context, args FuncArgs) (any, error) {
+		return "acme", nil
+	})
+
+	fn, ok := directive.Default.Lookup("tenant_test")
+	require.True(t, ok)
+	val, err := fn(context.Background(), FuncArgs{})
+	require.NoError(t, err)
+	require.Equal(t, "acme", val)
+}
+
+func TestRegisterFunc_Builtin(t *testing.T) {
+	require.Panics(t, func() {
+		RegisterFunc("eval", func(ctx context.Context, args FuncArgs) (any, error) { return nil, nil })
+	})
+	require.Panics(t, func() {
+		RegisterFunc("file", func(ctx context.Context, args FuncArgs) (any, error) { return nil, nil })
+	})
+}
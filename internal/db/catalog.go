@@ -0,0 +1,161 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Column describes a table column as seen in the database catalog
+type Column struct {
+	Name string
+	// DataType is the information_schema data type, e.g. "jsonb", "ARRAY", "integer", "json"
+	DataType string
+	// UDTName is the PostgreSQL underlying type ("_int4" for int[]) or the MySQL COLUMN_TYPE ("int unsigned")
+	UDTName string
+	// Nullable is false for NOT NULL columns
+	Nullable bool
+	// HasDefault is set when the database fills the column itself (DEFAULT, serial, identity, AUTO_INCREMENT)
+	HasDefault bool
+	// Generated is set for computed columns (GENERATED ALWAYS AS (...)), which can't be written
+	Generated bool
+	// Identity is "ALWAYS" or "BY DEFAULT" for PostgreSQL identity columns
+	Identity string
+	// PrimaryKey is set for the columns of the table's primary key
+	PrimaryKey bool
+}
+
+// Required reports whether every inserted row must provide a value for the column
+func (c Column) Required() bool {
+	return !c.Nullable && !c.HasDefault && !c.Generated
+}
+
+// Catalog maps a table name to its columns by name
+type Catalog map[string]map[string]Column
+
+// Column looks up a column of a table
+func (c Catalog) Column(table, column string) (Column, bool) {
+	col, ok := c[table][column]
+
+	return col, ok
+}
+
+// IsJSON reports whether the column holds JSON documents
+func (c Column) IsJSON() bool {
+	switch strings.ToLower(c.DataType) {
+	case "json", "jsonb":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsArray reports whether the column is a PostgreSQL array
+func (c Column) IsArray() bool {
+	return c.DataType == "ARRAY"
+}
+
+// IsText reports whether the column holds character data
+func (c Column) IsText() bool {
+	switch strings.ToLower(c.DataType) {
+	case "text", "character varying", "character", "varchar", "char",
+		"tinytext", "mediumtext", "longtext", "enum", "set", "citext":
+		return true
+	default:
+		return false
+	}
+}
+
+// columnKind groups database types that take the same Go values
+type columnKind int
+
+const (
+	kindOther columnKind = iota
+	kindInteger
+	kindNumeric
+	kindFloat
+	kindBool
+	kindDate
+	kindTimestamp
+	kindTime
+	kindInterval
+	kindUUID
+	kindJSON
+	kindArray
+	kindText
+	kindEnum
+	kindBinary
+)
+
+func (c Column) kind() columnKind {
+	switch strings.ToLower(c.DataType) {
+	case "smallint", "integer", "bigint", "tinyint", "mediumint", "int", "year":
+		return kindInteger
+	case "numeric", "decimal":
+		return kindNumeric
+	case "real", "double precision", "float", "double":
+		return kindFloat
+	case "boolean":
+		return kindBool
+	case "date":
+		return kindDate
+	case "timestamp without time zone", "timestamp with time zone", "timestamp", "datetime":
+		return kindTimestamp
+	case "time without time zone", "time with time zone", "time":
+		return kindTime
+	case "interval":
+		return kindInterval
+	case "uuid":
+		return kindUUID
+	case "json", "jsonb":
+		return kindJSON
+	case "array":
+		return kindArray
+	case "enum", "set":
+		return kindEnum
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return kindBinary
+	}
+
+	if c.IsText() {
+		return kindText
+	}
+
+	return kindOther
+}
+
+// scanCatalog reads (table, column, data_type, udt_name, nullable, has_default, generated,
+// identity, primary_key) rows,
+// keeping only the requested tables.
+// tableName maps the catalog table name to the name used in fixtures.
+func scanCatalog(rows *sql.Rows, tables []string, tableName func(string) string) (Catalog, error) {
+	wanted := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		wanted[t] = true
+	}
+
+	catalog := Catalog{}
+	for rows.Next() {
+		var table string
+		var col Column
+		if err := rows.Scan(&table, &col.Name, &col.DataType, &col.UDTName, &col.Nullable, &col.HasDefault, &col.Generated, &col.Identity, &col.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("scan column: %w", err)
+		}
+
+		table = tableName(table)
+		if !wanted[table] {
+			continue
+		}
+
+		if catalog[table] == nil {
+			catalog[table] = map[string]Column{}
+		}
+		catalog[table][col.Name] = col
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read columns: %w", err)
+	}
+
+	return catalog, nil
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	numericRe = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	uuidRe    = regexp.MustCompile(`^\{?[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}\}?$`)
+	enumRe    = regexp.MustCompile(`'((?:[^']|'')*)'`)
+)
+
+// exactNumber is a number that keeps its literal text, e.g. parser.Number or json.Number
+type exactNumber interface {
+	String() string
+	Float64() (float64, error)
+}
+
+// coerceScalar converts a scalar fixture value to the Go/driver type matching the column.
+// Values the database parses better itself (e.g. timestamps written as strings) are left as is.
+func coerceScalar(val any, col Column) (any, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	kind := col.kind()
+	switch kind {
+	case kindInteger:
+		return toInteger(val)
+	case kindNumeric:
+		return toNumeric(val)
+	case kindFloat:
+		return toFloat(val)
+	case kindBool:
+		return toBool(val)
+	case kindDate:
+		if t, ok := val.(time.Time); ok {
+			return t.Format("2006-01-02"), nil
+		}
+	case kindTime:
+		if t, ok := val.(time.Time); ok {
+			return t.Format("15:04:05.999999999"), nil
+		}
+	case kindTimestamp:
+		if _, ok := val.(bool); ok {
+			return nil, cannotConvert(val, col)
+		}
+	case kindInterval:
+		switch v := val.(type) {
+		case int, int64:
+			return fmt.Sprintf("%d seconds", v), nil
+		case exactNumber:
+			return v.String() + " seconds", nil
+		}
+	case kindUUID:
+		return toUUID(val, col)
+	case kindText, kindJSON, kindEnum:
+		s, ok := toText(val)
+		if !ok {
+			return val, nil
+		}
+		if kind == kindEnum {
+			return s, checkEnum(s, col)
+		}
+
+		return s, nil
+	}
+
+	if n, ok := val.(exactNumber); ok {
+		return n.String(), nil
+	}
+
+	return val, nil
+}
+
+func cannotConvert(val any, col Column) error {
+	return fmt.Errorf("cannot convert %T %v to %s", val, val, col.DataType)
+}
+
+func toInteger(val any) (any, error) {
+	switch v := val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	case bool:
+		// MySQL BOOLEAN is TINYINT(1)
+		if v {
+			return int64(1), nil
+		}
+
+		return int64(0), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+
+		return int64(v), nil
+	case exactNumber:
+		return parseInteger(v.String())
+	case string:
+		return parseInteger(strings.TrimSpace(v))
+	default:
+		return nil, fmt.Errorf("cannot convert %T %v to an integer", val, val)
+	}
+}
+
+// parseInteger keeps integers that don't fit into int64 as exact text
+func parseInteger(s string) (any, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || !r.IsInt() {
+		return nil, fmt.Errorf("%q is not an integer", s)
+	}
+	if r.Num().IsInt64() {
+		return r.Num().Int64(), nil
+	}
+
+	return r.Num().String(), nil
+}
+
+func toNumeric(val any) (any, error) {
+	switch v := val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case exactNumber:
+		return v.String(), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if !numericRe.MatchString(s) && !strings.EqualFold(s, "NaN") {
+			return nil, fmt.Errorf("%q is not a number", v)
+		}
+
+		return s, nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T %v to a number", val, val)
+	}
+}
+
+func toFloat(val any) (any, error) {
+	switch v := val.(type) {
+	case exactNumber:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", v.String())
+		}
+
+		return f, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", v)
+		}
+
+		return f, nil
+	case bool, time.Time:
+		return nil, fmt.Errorf("cannot convert %T %v to a number", val, val)
+	default:
+		return val, nil
+	}
+}
+
+func toBool(val any) (any, error) {
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case int, int64:
+		switch fmt.Sprint(v) {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot convert %T %v to a boolean", val, val)
+}
+
+func toUUID(val any, col Column) (any, error) {
+	switch v := val.(type) {
+	case string:
+		if !uuidRe.MatchString(strings.TrimSpace(v)) {
+			return nil, fmt.Errorf("%q is not a UUID", v)
+		}
+
+		return strings.TrimSpace(v), nil
+	case []byte:
+		if len(v) != 16 {
+			return nil, fmt.Errorf("%d bytes is not a UUID", len(v))
+		}
+
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), nil
+	default:
+		return nil, cannotConvert(val, col)
+	}
+}
+
+// toText renders scalars as the text a user would expect to find in a character column
+func toText(val any) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case exactNumber:
+		return v.String(), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	default:
+		return "", false
+	}
+}
+
+// checkEnum validates a value against MySQL enum('a','b') / set('a','b') column types
+func checkEnum(s string, col Column) error {
+	matches := enumRe.FindAllStringSubmatch(col.UDTName, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(matches))
+	labels := make([]string, 0, len(matches))
+	for _, m := range matches {
+		label := strings.ReplaceAll(m[1], "''", "'")
+		allowed[label] = true
+		labels = append(labels, label)
+	}
+
+	values := []string{s}
+	if strings.EqualFold(col.DataType, "set") {
+		values = strings.Split(s, ",")
+	}
+	for _, v := range values {
+		if v != "" && !allowed[v] {
+			return fmt.Errorf("%q is not one of %s", v, strings.Join(labels, ", "))
+		}
+	}
+
+	return nil
+}
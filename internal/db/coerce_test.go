@@ -0,0 +1,70 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertValue_Coercion(t *testing.T) {
+	col := func(dataType, udtName string) *Column {
+		return &Column{Name: "c", DataType: dataType, UDTName: udtName}
+	}
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		val       any
+		col       *Column
+		expected  any
+		expectErr string
+	}{
+		{name: "exact number to numeric", val: json.Number("399.99"), col: col("numeric", "numeric"), expected: "399.99"},
+		{name: "float to decimal", val: 2.5, col: col("decimal", "decimal(10,2)"), expected: "2.5"},
+		{name: "numeric string", val: "12.50", col: col("numeric", "numeric"), expected: "12.50"},
+		{name: "bad numeric string", val: "12,50", col: col("numeric", "numeric"), expectErr: "not a number"},
+		{name: "big integer keeps text", val: json.Number("123456789012345678901234"), col: col("numeric", "numeric"), expected: "123456789012345678901234"},
+		{name: "int to bigint", val: 7, col: col("bigint", "int8"), expected: 7},
+		{name: "integral float to int", val: json.Number("3.0"), col: col("integer", "int4"), expected: int64(3)},
+		{name: "string to int", val: "42", col: col("integer", "int4"), expected: int64(42)},
+		{name: "huge integer text", val: json.Number("99999999999999999999"), col: col("bigint", "int8"), expected: "99999999999999999999"},
+		{name: "fraction to int", val: json.Number("3.5"), col: col("integer", "int4"), expectErr: "not an integer"},
+		{name: "bool to tinyint", val: true, col: col("tinyint", "tinyint(1)"), expected: int64(1)},
+		{name: "number to float", val: json.Number("1.25"), col: col("double precision", "float8"), expected: 1.25},
+		{name: "string to bool", val: "yes", col: col("boolean", "bool"), expected: true},
+		{name: "bad bool", val: "maybe", col: col("boolean", "bool"), expectErr: "to a boolean"},
+		{name: "yaml date to date", val: day, col: col("date", "date"), expected: "2024-01-01"},
+		{name: "time to time of day", val: day.Add(90 * time.Minute), col: col("time without time zone", "time"), expected: "01:30:00"},
+		{name: "yaml date to timestamp", val: day, col: col("timestamp with time zone", "timestamptz"), expected: day},
+		{name: "yaml date to text", val: day, col: col("text", "text"), expected: "2024-01-01T00:00:00Z"},
+		{name: "number to text", val: json.Number("1.10"), col: col("character varying", "varchar"), expected: "1.10"},
+		{name: "int to text", val: 10, col: col("text", "text"), expected: "10"},
+		{name: "valid uuid", val: "6F9619FF-8B86-D011-B42D-00C04FC964FF", col: col("uuid", "uuid"), expected: "6F9619FF-8B86-D011-B42D-00C04FC964FF"},
+		{name: "invalid uuid", val: "not-a-uuid", col: col("uuid", "uuid"), expectErr: "not a UUID"},
+		{name: "uuid from bytes", val: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, col: col("uuid", "uuid"), expected: "01020304-0506-0708-090a-0b0c0d0e0f10"},
+		{name: "seconds to interval", val: 90, col: col("interval", "interval"), expected: "90 seconds"},
+		{name: "string interval", val: "1 day", col: col("interval", "interval"), expected: "1 day"},
+		{name: "mysql enum", val: "active", col: col("enum", "enum('active','blocked')"), expected: "active"},
+		{name: "mysql enum mismatch", val: "deleted", col: col("enum", "enum('active','blocked')"), expectErr: `"deleted" is not one of active, blocked`},
+		{name: "mysql set", val: "a,b", col: col("set", "set('a','b','c')"), expected: "a,b"},
+		{name: "nil", val: nil, col: col("integer", "int4"), expected: nil},
+		{name: "exact number to unknown type", val: json.Number("1.5"), col: col("USER-DEFINED", "money"), expected: "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertValue(tt.val, tt.col)
+			if tt.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.expectErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConvertValue prepares a fixture value for the driver according to the target column.
+// col is nil when the column is unknown to the catalog.
+//
+//   - maps and lists bound for json/jsonb/JSON columns are JSON-encoded
+//   - lists bound for PostgreSQL array columns become array literals ({"a","b"})
+//   - []byte bound for JSON or character columns is passed as text
+//   - scalars are coerced to the column type (see coerceScalar), exact numbers keep their text
+//
+// Maps and lists for other columns are JSON-encoded as well, since drivers can't bind them anyway.
+func ConvertValue(val any, col *Column) (any, error) {
+	switch v := val.(type) {
+	case map[string]any, map[any]any:
+		return encodeJSON(v)
+	case []any:
+		if col != nil && col.IsArray() {
+			return arrayLiteral(v)
+		}
+
+		return encodeJSON(v)
+	case []byte:
+		if col != nil && (col.IsJSON() || col.IsText()) {
+			return string(v), nil
+		}
+		if col != nil && col.kind() == kindUUID {
+			return toUUID(v, *col)
+		}
+
+		return v, nil
+	default:
+		if col == nil {
+			return val, nil
+		}
+
+		return coerceScalar(val, *col)
+	}
+}
+
+func encodeJSON(val any) (string, error) {
+	data, err := json.Marshal(jsonCompatible(val))
+	if err != nil {
+		return "", fmt.Errorf("encode JSON: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// jsonCompatible converts map[any]any produced by YAML for non-string keys into map[string]any
+func jsonCompatible(val any) any {
+	switch v := val.(type) {
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[fmt.Sprint(k)] = jsonCompatible(item)
+		}
+
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = jsonCompatible(item)
+		}
+
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = jsonCompatible(item)
+		}
+
+		return out
+	case []byte:
+		return string(v)
+	default:
+		return val
+	}
+}
+
+// arrayLiteral renders a PostgreSQL array literal, e.g. {"a","b"} or {{"1","2"},{"3","4"}}.
+// Elements are always quoted, PostgreSQL casts them to the element type.
+func arrayLiteral(items []any) (string, error) {
+	var sb strings.Builder
+	if err := writeArray(&sb, items); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func writeArray(sb *strings.Builder, items []any) error {
+	sb.WriteByte('{')
+	for i, item := range items {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+
+		var elem string
+		switch v := item.(type) {
+		case nil:
+			sb.WriteString("NULL")
+			continue
+		case []any:
+			if err := writeArray(sb, v); err != nil {
+				return err
+			}
+			continue
+		case map[string]any, map[any]any:
+			encoded, err := encodeJSON(v)
+			if err != nil {
+				return err
+			}
+			elem = encoded
+		case string:
+			elem = v
+		case []byte:
+			elem = string(v)
+		case time.Time:
+			elem = v.Format(time.RFC3339Nano)
+		case float64:
+			elem = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			elem = fmt.Sprint(v)
+		}
+
+		sb.WriteByte('"')
+		sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(elem))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertValue(t *testing.T) {
+	jsonb := &Column{Name: "meta", DataType: "jsonb", UDTName: "jsonb"}
+	mysqlJSON := &Column{Name: "meta", DataType: "json", UDTName: "json"}
+	textArray := &Column{Name: "tags", DataType: "ARRAY", UDTName: "_text"}
+	intArray := &Column{Name: "ids", DataType: "ARRAY", UDTName: "_int4"}
+	text := &Column{Name: "body", DataType: "text", UDTName: "text"}
+	bytea := &Column{Name: "content", DataType: "bytea", UDTName: "bytea"}
+
+	tests := []struct {
+		name     string
+		val      any
+		col      *Column
+		expected any
+	}{
+		{
+			name:     "map to jsonb",
+			val:      map[string]any{"b": 1, "a": []any{"x", true}},
+			col:      jsonb,
+			expected: `{"a":["x",true],"b":1}`,
+		},
+		{
+			name:     "list to mysql json",
+			val:      []any{1, "two", nil},
+			col:      mysqlJSON,
+			expected: `[1,"two",null]`,
+		},
+		{
+			name:     "non-string keys",
+			val:      map[any]any{1: "one"},
+			col:      jsonb,
+			expected: `{"1":"one"}`,
+		},
+		{
+			name:     "text array",
+			val:      []any{"a", `quo"te`, `back\slash`, nil},
+			col:      textArray,
+			expected: `{"a","quo\"te","back\\slash",NULL}`,
+		},
+		{
+			name:     "nested int array",
+			val:      []any{[]any{1, 2}, []any{3, 4.5}},
+			col:      intArray,
+			expected: `{{"1","2"},{"3","4.5"}}`,
+		},
+		{
+			name:     "time in array",
+			val:      []any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			col:      textArray,
+			expected: `{"2024-01-01T00:00:00Z"}`,
+		},
+		{
+			name:     "map to unknown column",
+			val:      map[string]any{"a": 1},
+			col:      nil,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "bytes to jsonb",
+			val:      []byte(`{"a":1}`),
+			col:      jsonb,
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "bytes to text",
+			val:      []byte("hello"),
+			col:      text,
+			expected: "hello",
+		},
+		{
+			name:     "bytes to bytea",
+			val:      []byte("hello"),
+			col:      bytea,
+			expected: []byte("hello"),
+		},
+		{
+			name:     "scalar to jsonb",
+			val:      42,
+			col:      jsonb,
+			expected: "42",
+		},
+		{
+			name:     "scalar to unknown column",
+			val:      42,
+			col:      nil,
+			expected: 42,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertValue(tt.val, tt.col)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
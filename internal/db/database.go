@@ -3,83 +3,273 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ExprRewriter is implemented by databases that need to adapt $eval expressions,
+// which are conventionally written in PostgreSQL syntax
+type ExprRewriter interface {
+	RewriteExpr(expr string) string
+}
+
 // Database defines the interface for database-specific operations
 type Database interface {
 	// GetDependencyGraph returns a map of table dependencies
 	GetDependencyGraph(ctx context.Context, db *sql.DB) (map[string][]string, error)
 
+	// GetForeignKeys returns the foreign keys of the given tables, with their columns
+	GetForeignKeys(ctx context.Context, db *sql.DB, tables []string) ([]ForeignKey, error)
+
+	// ResolveTables maps fixture table names (users, public.users, "public"."users") to the
+	// canonical schema.table names used by the dependency graph and the catalog
+	ResolveTables(ctx context.Context, db *sql.DB, tables []string) (map[string]string, error)
+
+	// GetColumns returns the catalog description of the columns of the given tables
+	GetColumns(ctx context.Context, db *sql.DB, tables []string) (Catalog, error)
+
 	// TruncateTables generates and executes a SQL statement to truncate the given tables
-	TruncateTables(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error
+	TruncateTables(ctx context.Context, q Querier, tables []string, dryRun bool) error
+
+	// CleanupStatements returns the statements TruncateTables runs for the given tables
+	CleanupStatements(tables []string) []string
+
+	// DeferConstraints postpones the checks of DEFERRABLE constraints until the transaction commits
+	DeferConstraints(ctx context.Context, q Querier, dryRun bool) error
+
+	// InsertRow generates and executes a SQL statement to insert a row into a table.
+	// columns is the catalog entry of the table (nil if unknown).
+	InsertRow(ctx context.Context, q Querier, table string, row map[string]any, columns map[string]Column, dryRun bool) error
+
+	// UpdateRow sets values on the row identified by the key columns
+	UpdateRow(ctx context.Context, q Querier, table string, key, values map[string]any, dryRun bool) error
+
+	// ResetSequences moves the sequences (auto-increment counters) of the given tables past
+	// the loaded rows and reports what was reset
+	ResetSequences(ctx context.Context, q Querier, tables []string, dryRun bool) ([]SequenceReset, error)
 
-	// InsertRow generates and executes a SQL statement to insert a row into a table
-	InsertRow(ctx context.Context, tx *sql.Tx, table string, row map[string]any, dryRun bool) error
+	// OwnedSequences lists the sequences (auto-increment columns) ResetSequences moves for the
+	// given tables, without reading or changing their values
+	OwnedSequences(ctx context.Context, db *sql.DB, tables []string) ([]SequenceReset, error)
 
-	// ResetSequences resets the auto-increment sequences for the given tables
-	ResetSequences(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error
+	// ResolveSequence finds the sequence (auto-increment counter) named in a fixture's sequences
+	// section, either by sequence name or as table.column
+	ResolveSequence(ctx context.Context, db *sql.DB, name string) (SequenceReset, error)
+
+	// SetSequence makes the resolved sequence produce seq.Next on the next insert
+	SetSequence(ctx context.Context, q Querier, seq SequenceReset, dryRun bool) error
+
+	// SetTimeouts applies the limits to q. In a PostgreSQL transaction they end with it,
+	// otherwise they stay on the connection until ResetTimeouts.
+	SetTimeouts(ctx context.Context, q Querier, timeouts Timeouts, dryRun bool) error
+
+	// ResetTimeouts restores the server settings changed by SetTimeouts
+	ResetTimeouts(ctx context.Context, q Querier, timeouts Timeouts, dryRun bool) error
 
 	// Placeholder returns the parameter placeholder for the given index
 	Placeholder(index int) string
+
+	// QuoteIdent quotes a single identifier (table, column or schema name)
+	QuoteIdent(ident string) string
+
+	// IsTransient reports whether err is a serialization failure or a deadlock, after which
+	// the same load can succeed when tried again
+	IsTransient(err error) bool
+
+	// Lock takes the named lock, waiting at most wait (forever if zero). The lock belongs to the
+	// session: q must be a connection of its own, held until Unlock or closed to release the lock.
+	Lock(ctx context.Context, q Querier, name string, wait time.Duration, dryRun bool) error
+
+	// Unlock releases the lock taken by Lock on the same connection
+	Unlock(ctx context.Context, q Querier, name string, dryRun bool) error
+
+	// ImplicitCommits reports which steps of a load commit the open transaction on their own
+	ImplicitCommits() ImplicitCommits
+}
+
+// ImplicitCommits lists the steps of a load that run statements committing the open transaction
+type ImplicitCommits struct {
+	// Cleanup is set when TruncateTables commits (TRUNCATE TABLE on MySQL)
+	Cleanup bool
+	// Sequences is set when ResetSequences and SetSequence commit (ALTER TABLE on MySQL)
+	Sequences bool
 }
 
 // PostgresDatabase implements the Database interface for PostgreSQL
 type PostgresDatabase struct{}
 
-// GetDependencyGraph implements Database.GetDependencyGraph for PostgreSQL
+// GetDependencyGraph implements Database.GetDependencyGraph for PostgreSQL.
+// The graph is built from the same catalog query as GetForeignKeys, see foreignKeys.
 func (p *PostgresDatabase) GetDependencyGraph(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	keys, err := p.foreignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return dependencyGraph(keys), nil
+}
+
+// GetForeignKeys implements Database.GetForeignKeys for PostgreSQL
+func (p *PostgresDatabase) GetForeignKeys(ctx context.Context, db *sql.DB, tables []string) ([]ForeignKey, error) {
+	keys, err := p.foreignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterForeignKeys(keys, tables), nil
+}
+
+// foreignKeys reads all foreign keys from pg_constraint. Unlike information_schema, the
+// system catalogs show constraints of tables the current role doesn't own.
+// Partitions carry copies of the constraints of their partitioned table, on both sides
+// of the reference, so rows loaded into a partition directly are ordered as well.
+func (p *PostgresDatabase) foreignKeys(ctx context.Context, db *sql.DB) ([]ForeignKey, error) {
 	query := `
 SELECT
-    tc.table_schema || '.' || tc.table_name AS child,
-    ccu.table_schema || '.' || ccu.table_name AS parent
+    c.conname,
+    cn.nspname || '.' || cl.relname,
+    fn.nspname || '.' || fl.relname,
+    a.attname,
+    fa.attname,
+    c.condeferrable
 FROM
-    information_schema.table_constraints tc
-    JOIN information_schema.key_column_usage kcu
-        ON tc.constraint_name = kcu.constraint_name
-        AND tc.constraint_schema = kcu.constraint_schema
-    JOIN information_schema.constraint_column_usage ccu
-        ON ccu.constraint_name = tc.constraint_name
-        AND ccu.constraint_schema = tc.constraint_schema
+    pg_constraint c
+    JOIN pg_class cl ON cl.oid = c.conrelid
+    JOIN pg_namespace cn ON cn.oid = cl.relnamespace
+    JOIN pg_class fl ON fl.oid = c.confrelid
+    JOIN pg_namespace fn ON fn.oid = fl.relnamespace
+    CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+    JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+    JOIN pg_attribute fa ON fa.attrelid = c.confrelid AND fa.attnum = k.fattnum
 WHERE
-    tc.constraint_type = 'FOREIGN KEY'
+    c.contype = 'f'
+ORDER BY
+    cn.nspname, cl.relname, c.conname, fn.nspname, fl.relname, k.ord
 `
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("query dependencies: %w", err)
+		return nil, fmt.Errorf("query foreign keys: %w", err)
 	}
 	defer rows.Close()
 
-	graph := map[string][]string{}
-	for rows.Next() {
-		var child, parent string
-		if err := rows.Scan(&child, &parent); err != nil {
-			return nil, fmt.Errorf("scan dependency: %w", err)
+	return scanForeignKeys(rows)
+}
+
+// ResolveTables implements Database.ResolveTables for PostgreSQL.
+// Unqualified names are looked up through the connection's search_path.
+func (p *PostgresDatabase) ResolveTables(ctx context.Context, db *sql.DB, tables []string) (map[string]string, error) {
+	query := `
+SELECT
+    n.nspname || '.' || c.relname
+FROM
+    pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE
+    c.oid = to_regclass($1)
+`
+	resolved := make(map[string]string, len(tables))
+	for _, table := range tables {
+		var name string
+		err := db.QueryRowContext(ctx, query, quoteQualified(table, p.QuoteIdent)).Scan(&name)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Unknown table: keep the name as written, the schema check reports it
+			name = strings.Join(SplitQualified(table), ".")
+		case err != nil:
+			return nil, fmt.Errorf("resolve table %q: %w", table, err)
 		}
 
-		graph[child] = append(graph[child], parent)
+		resolved[table] = name
 	}
 
-	return graph, nil
+	return resolved, nil
+}
+
+// GetColumns implements Database.GetColumns for PostgreSQL. Primary keys are read from
+// pg_constraint like in foreignKeys: information_schema hides them on tables of other roles.
+func (p *PostgresDatabase) GetColumns(ctx context.Context, db *sql.DB, tables []string) (Catalog, error) {
+	query := `
+SELECT
+    table_schema || '.' || table_name AS table_name,
+    column_name,
+    data_type,
+    udt_name,
+    is_nullable = 'YES' AS nullable,
+    column_default IS NOT NULL OR is_identity = 'YES' AS has_default,
+    is_generated <> 'NEVER' AS generated,
+    COALESCE(identity_generation, '') AS identity,
+    EXISTS (
+        SELECT 1
+        FROM
+            pg_constraint pk
+            JOIN pg_class t ON t.oid = pk.conrelid
+            JOIN pg_namespace n ON n.oid = t.relnamespace
+            JOIN pg_attribute a ON a.attrelid = pk.conrelid AND a.attnum = ANY (pk.conkey)
+        WHERE
+            pk.contype = 'p'
+            AND n.nspname = c.table_schema
+            AND t.relname = c.table_name
+            AND a.attname = c.column_name
+    ) AS primary_key
+FROM
+    information_schema.columns c
+WHERE
+    table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY
+    table_schema, table_name, ordinal_position
+`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query columns: %w", err)
+	}
+	defer rows.Close()
+
+	return scanCatalog(rows, tables, func(table string) string { return table })
 }
 
 // TruncateTables implements Database.TruncateTables for PostgreSQL
-func (p *PostgresDatabase) TruncateTables(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error {
-	query := "TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"
+func (p *PostgresDatabase) TruncateTables(ctx context.Context, q Querier, tables []string, dryRun bool) error {
+	query := p.truncateQuery(tables)
 	if dryRun {
 		log.Println("[dry-run]", query)
 		return nil
 	}
 
-	_, err := tx.ExecContext(ctx, query)
+	_, err := q.ExecContext(ctx, query)
 	return err
 }
 
+// CleanupStatements implements Database.CleanupStatements for PostgreSQL
+func (p *PostgresDatabase) CleanupStatements(tables []string) []string {
+	return []string{p.truncateQuery(tables)}
+}
+
+// truncateQuery truncates all tables in one statement
+func (p *PostgresDatabase) truncateQuery(tables []string) string {
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, quoteQualified(table, p.QuoteIdent))
+	}
+
+	return "TRUNCATE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE"
+}
+
 // InsertRow implements Database.InsertRow for PostgreSQL
-func (p *PostgresDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table string, row map[string]any, dryRun bool) error {
+func (p *PostgresDatabase) InsertRow(ctx context.Context, q Querier, table string, row map[string]any, columns map[string]Column, dryRun bool) error {
 	cols := make([]string, 0, len(row))
 	for col := range row {
 		cols = append(cols, col)
@@ -87,15 +277,24 @@ func (p *PostgresDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table stri
 	sort.Strings(cols)
 
 	vals := make([]any, 0, len(row))
+	quoted := make([]string, 0, len(row))
 	ph := make([]string, 0, len(row))
+	overriding := ""
 	for i, col := range cols {
 		vals = append(vals, row[col])
+		quoted = append(quoted, p.QuoteIdent(col))
 		ph = append(ph, p.Placeholder(i+1))
+
+		// GENERATED ALWAYS identity columns only accept explicit values with this clause
+		if columns[col].Identity == "ALWAYS" {
+			overriding = " OVERRIDING SYSTEM VALUE"
+		}
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		table,
-		strings.Join(cols, ", "),
+	query := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)",
+		quoteQualified(table, p.QuoteIdent),
+		strings.Join(quoted, ", "),
+		overriding,
 		strings.Join(ph, ", "),
 	)
 
@@ -104,39 +303,244 @@ func (p *PostgresDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table stri
 		return nil
 	}
 
-	_, err := tx.ExecContext(ctx, query, vals...)
+	_, err := q.ExecContext(ctx, query, vals...)
+	return err
+}
+
+// DeferConstraints implements Database.DeferConstraints for PostgreSQL
+func (p *PostgresDatabase) DeferConstraints(ctx context.Context, q Querier, dryRun bool) error {
+	query := "SET CONSTRAINTS ALL DEFERRED"
+	if dryRun {
+		log.Println("[dry-run]", query)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query)
 	return err
 }
 
-// ResetSequences implements Database.ResetSequences for PostgreSQL
-func (p *PostgresDatabase) ResetSequences(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error {
+// UpdateRow implements Database.UpdateRow for PostgreSQL
+func (p *PostgresDatabase) UpdateRow(ctx context.Context, q Querier, table string, key, values map[string]any, dryRun bool) error {
+	query, vals := updateQuery(table, key, values, p.QuoteIdent, p.Placeholder)
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query, vals...)
+	return err
+}
+
+// ResetSequences implements Database.ResetSequences for PostgreSQL.
+// Sequences are found through pg_depend, so serial columns, identity columns and sequences
+// attached with OWNED BY are all covered.
+func (p *PostgresDatabase) ResetSequences(ctx context.Context, q Querier, tables []string, dryRun bool) ([]SequenceReset, error) {
+	var resets []SequenceReset
+	for _, schemaTable := range tables {
+		table := quoteQualified(schemaTable, p.QuoteIdent)
+
+		sequences, err := p.ownedSequences(ctx, q, table)
+		if err != nil {
+			return nil, fmt.Errorf("query sequences of %s: %w", schemaTable, err)
+		}
+
+		for _, seq := range sequences {
+			// Descending sequences continue below the smallest value
+			agg := "MAX"
+			if seq.increment < 0 {
+				agg = "MIN"
+			}
+
+			var last sql.NullInt64
+			lastQuery := fmt.Sprintf("SELECT %s(%s) FROM %s", agg, p.QuoteIdent(seq.column), table)
+			if err := q.QueryRowContext(ctx, lastQuery).Scan(&last); err != nil {
+				return nil, fmt.Errorf("get last value of %s.%s: %w", schemaTable, seq.column, err)
+			}
+
+			// An empty table restarts the sequence: is_called = false makes nextval return start itself
+			value, isCalled, next := seq.start, false, seq.start
+			if last.Valid {
+				value, isCalled, next = last.Int64, true, last.Int64+seq.increment
+			}
+
+			setQuery := "SELECT setval($1::regclass, $2, $3)"
+			vals := []any{seq.name, value, isCalled}
+			if dryRun {
+				log.Printf("[dry-run] %s :: %v", setQuery, vals)
+			} else if _, err := q.ExecContext(ctx, setQuery, vals...); err != nil {
+				return nil, fmt.Errorf("reset sequence %s: %w", seq.name, err)
+			}
+
+			resets = append(resets, SequenceReset{
+				Table:    schemaTable,
+				Column:   seq.column,
+				Sequence: seq.name,
+				Next:     next,
+			})
+		}
+	}
+
+	return resets, nil
+}
+
+// OwnedSequences implements Database.OwnedSequences for PostgreSQL
+func (p *PostgresDatabase) OwnedSequences(ctx context.Context, db *sql.DB, tables []string) ([]SequenceReset, error) {
+	var owned []SequenceReset
 	for _, schemaTable := range tables {
-		parts := strings.Split(schemaTable, ".")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid table name: %q", schemaTable)
+		sequences, err := p.ownedSequences(ctx, db, quoteQualified(schemaTable, p.QuoteIdent))
+		if err != nil {
+			return nil, fmt.Errorf("query sequences of %s: %w", schemaTable, err)
 		}
 
-		query := fmt.Sprintf(`
-DO $$
-DECLARE
-    r record;
-BEGIN
-    FOR r IN
-        SELECT column_default, column_name FROM information_schema.columns
-        WHERE table_schema = '%s' AND table_name = '%s' AND column_default LIKE 'nextval%%'
-    LOOP
-        EXECUTE format('SELECT setval(pg_get_serial_sequence(''%s'', ''%s''), COALESCE(MAX(%s), 1)) FROM %s',
-            r.column_name, r.column_name, r.column_name, '%s');
-    END LOOP;
-END$$;
-`, parts[0], parts[1], schemaTable, "%s", "%s", schemaTable, schemaTable)
+		for _, seq := range sequences {
+			owned = append(owned, SequenceReset{Table: schemaTable, Column: seq.column, Sequence: seq.name})
+		}
+	}
+
+	return owned, nil
+}
+
+// ResolveSequence implements Database.ResolveSequence for PostgreSQL.
+// A name of up to two parts is looked up as a sequence first and as a serial or identity column
+// second. Three parts can only be schema.table.column: to_regclass reads them as a reference to
+// another database and fails.
+func (p *PostgresDatabase) ResolveSequence(ctx context.Context, db *sql.DB, name string) (SequenceReset, error) {
+	parts := SplitQualified(name)
+	switch {
+	case len(parts) > 3:
+		return SequenceReset{}, fmt.Errorf("invalid sequence %q: expected a sequence or table.column", name)
+	case len(parts) == 3:
+		return p.resolveSequenceColumn(ctx, db, name, parts)
+	}
+
+	bySequence := `
+SELECT
+    s.oid::regclass::text,
+    COALESCE(n.nspname || '.' || t.relname, ''),
+    COALESCE(a.attname, '')
+FROM
+    pg_class s
+    LEFT JOIN pg_depend d
+        ON d.objid = s.oid
+        AND d.classid = 'pg_class'::regclass
+        AND d.refclassid = 'pg_class'::regclass
+        AND d.deptype IN ('a', 'i')
+    LEFT JOIN pg_class t ON t.oid = d.refobjid
+    LEFT JOIN pg_namespace n ON n.oid = t.relnamespace
+    LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+WHERE
+    s.oid = to_regclass($1)
+    AND s.relkind = 'S'
+`
+	var seq SequenceReset
+	err := db.QueryRowContext(ctx, bySequence, quoteQualified(name, p.QuoteIdent)).Scan(&seq.Sequence, &seq.Table, &seq.Column)
+	switch {
+	case err == nil:
+		return seq, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return SequenceReset{}, fmt.Errorf("resolve sequence %q: %w", name, err)
+	case len(parts) < 2:
+		return SequenceReset{}, fmt.Errorf("unknown sequence %q", name)
+	}
+
+	return p.resolveSequenceColumn(ctx, db, name, parts)
+}
+
+// resolveSequenceColumn finds the sequence owned by the serial or identity column named by parts
+// (table.column or schema.table.column)
+func (p *PostgresDatabase) resolveSequenceColumn(ctx context.Context, db *sql.DB, name string, parts []string) (SequenceReset, error) {
+	table := strings.Join(parts[:len(parts)-1], ".")
+	column := parts[len(parts)-1]
+
+	byColumn := `
+SELECT
+    s.oid::regclass::text,
+    n.nspname || '.' || t.relname
+FROM
+    pg_depend d
+    JOIN pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+    JOIN pg_class t ON t.oid = d.refobjid
+    JOIN pg_namespace n ON n.oid = t.relnamespace
+    JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+WHERE
+    d.classid = 'pg_class'::regclass
+    AND d.refclassid = 'pg_class'::regclass
+    AND d.deptype IN ('a', 'i')
+    AND d.refobjid = to_regclass($1)
+    AND a.attname = $2
+`
+	seq := SequenceReset{Column: column}
+	err := db.QueryRowContext(ctx, byColumn, quoteQualified(table, p.QuoteIdent), column).Scan(&seq.Sequence, &seq.Table)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return SequenceReset{}, fmt.Errorf("unknown sequence %q: neither a sequence nor a serial or identity column", name)
+	case err != nil:
+		return SequenceReset{}, fmt.Errorf("resolve sequence %q: %w", name, err)
+	}
+
+	return seq, nil
+}
 
+// SetSequence implements Database.SetSequence for PostgreSQL
+func (p *PostgresDatabase) SetSequence(ctx context.Context, q Querier, seq SequenceReset, dryRun bool) error {
+	// is_called = false: the next nextval returns the value itself
+	query := "SELECT setval($1::regclass, $2, false)"
+	vals := []any{seq.Sequence, seq.Next}
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query, vals...)
+	return err
+}
+
+// SetTimeouts implements Database.SetTimeouts for PostgreSQL: SET LOCAL in a transaction,
+// SET on a connection
+func (p *PostgresDatabase) SetTimeouts(ctx context.Context, q Querier, timeouts Timeouts, dryRun bool) error {
+	set := "SET "
+	if _, ok := q.(*sql.Tx); ok {
+		set = "SET LOCAL "
+	}
+
+	var queries []string
+	if timeouts.Statement > 0 {
+		queries = append(queries, fmt.Sprintf("%sstatement_timeout = %d", set, millis(timeouts.Statement)))
+	}
+	if timeouts.Lock > 0 {
+		queries = append(queries, fmt.Sprintf("%slock_timeout = %d", set, millis(timeouts.Lock)))
+	}
+
+	return p.execAll(ctx, q, queries, dryRun)
+}
+
+// ResetTimeouts implements Database.ResetTimeouts for PostgreSQL, settings made with
+// SET LOCAL end with the transaction
+func (p *PostgresDatabase) ResetTimeouts(ctx context.Context, q Querier, timeouts Timeouts, dryRun bool) error {
+	if _, ok := q.(*sql.Tx); ok {
+		return nil
+	}
+
+	var queries []string
+	if timeouts.Statement > 0 {
+		queries = append(queries, "RESET statement_timeout")
+	}
+	if timeouts.Lock > 0 {
+		queries = append(queries, "RESET lock_timeout")
+	}
+
+	return p.execAll(ctx, q, queries, dryRun)
+}
+
+// execAll executes the queries in order, or only logs them in dry-run mode
+func (p *PostgresDatabase) execAll(ctx context.Context, q Querier, queries []string, dryRun bool) error {
+	for _, query := range queries {
 		if dryRun {
 			log.Println("[dry-run]", query)
 			continue
 		}
 
-		if _, err := tx.ExecContext(ctx, query); err != nil {
+		if _, err := q.ExecContext(ctx, query); err != nil {
 			return err
 		}
 	}
@@ -144,34 +548,172 @@ END$$;
 	return nil
 }
 
+type ownedSequence struct {
+	name      string
+	column    string
+	start     int64
+	increment int64
+}
+
+// ownedSequences returns the sequences owned by the columns of table (quoted)
+func (p *PostgresDatabase) ownedSequences(ctx context.Context, q Querier, table string) ([]ownedSequence, error) {
+	// deptype 'a' links serial and OWNED BY sequences to their column, 'i' identity sequences
+	query := `
+SELECT
+    s.oid::regclass::text,
+    a.attname,
+    seq.seqstart,
+    seq.seqincrement
+FROM
+    pg_depend d
+    JOIN pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+    JOIN pg_sequence seq ON seq.seqrelid = s.oid
+    JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+WHERE
+    d.classid = 'pg_class'::regclass
+    AND d.refclassid = 'pg_class'::regclass
+    AND d.deptype IN ('a', 'i')
+    AND d.refobjid = to_regclass($1)
+ORDER BY
+    a.attnum
+`
+	rows, err := q.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sequences []ownedSequence
+	for rows.Next() {
+		var seq ownedSequence
+		if err := rows.Scan(&seq.name, &seq.column, &seq.start, &seq.increment); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, seq)
+	}
+
+	return sequences, rows.Err()
+}
+
+// IsTransient implements Database.IsTransient for PostgreSQL: serialization failures (40001)
+// and deadlocks (40P01). The SQLSTATE is read through the SQLState method of the driver error
+// (lib/pq and pgx both have it).
+func (p *PostgresDatabase) IsTransient(err error) bool {
+	code := sqlState(err)
+	return code == "40001" || code == "40P01"
+}
+
+// sqlState returns the SQLSTATE of a PostgreSQL driver error, "" for other errors
+func sqlState(err error) string {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return ""
+	}
+
+	return pgErr.SQLState()
+}
+
+// Lock implements Database.Lock for PostgreSQL with a session-level advisory lock keyed by the
+// hash of the name. The wait is limited with lock_timeout, so waiting loaders queue up in order.
+func (p *PostgresDatabase) Lock(ctx context.Context, q Querier, name string, wait time.Duration, dryRun bool) (err error) {
+	if wait > 0 {
+		if err := p.execAll(ctx, q, []string{fmt.Sprintf("SET lock_timeout = %d", millis(wait))}, dryRun); err != nil {
+			return err
+		}
+		defer func() {
+			if resetErr := p.execAll(context.WithoutCancel(ctx), q, []string{"RESET lock_timeout"}, dryRun); err == nil {
+				err = resetErr
+			}
+		}()
+	}
+
+	query := "SELECT pg_advisory_lock(hashtext($1))"
+	vals := []any{name}
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	_, err = q.ExecContext(ctx, query, vals...)
+	if sqlState(err) == "55P03" {
+		return fmt.Errorf("not acquired within %s: %w", wait, err)
+	}
+
+	return err
+}
+
+// Unlock implements Database.Unlock for PostgreSQL
+func (p *PostgresDatabase) Unlock(ctx context.Context, q Querier, name string, dryRun bool) error {
+	query := "SELECT pg_advisory_unlock(hashtext($1))"
+	vals := []any{name}
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query, vals...)
+	return err
+}
+
+// ImplicitCommits implements Database.ImplicitCommits for PostgreSQL, where TRUNCATE and setval
+// are transactional
+func (p *PostgresDatabase) ImplicitCommits() ImplicitCommits {
+	return ImplicitCommits{}
+}
+
 // Placeholder implements Database.Placeholder for PostgreSQL
 func (p *PostgresDatabase) Placeholder(index int) string {
 	return fmt.Sprintf("$%d", index)
 }
 
-// MySQLDatabase implements the Database interface for MySQL
-type MySQLDatabase struct{}
+// QuoteIdent implements Database.QuoteIdent for PostgreSQL
+func (p *PostgresDatabase) QuoteIdent(ident string) string {
+	return quotePostgres(ident)
+}
 
-// GetDependencyGraph implements Database.GetDependencyGraph for MySQL
-func (m *MySQLDatabase) GetDependencyGraph(ctx context.Context, db *sql.DB) (map[string][]string, error) {
-	// For MySQL, we need to get the current database name
-	var dbName string
-	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
-		return nil, fmt.Errorf("get current database: %w", err)
+// MySQLDatabase implements the Database interface for MySQL.
+// MySQL has no schemas inside a database, so tables are qualified by database name (db.table).
+type MySQLDatabase struct {
+	// SchemaAliases maps schema names used in fixtures to databases, an empty target meaning
+	// the current database. Unless it maps "public" itself, "public" is an alias of the current
+	// database, so fixtures written for PostgreSQL keep working.
+	SchemaAliases map[string]string
+
+	// UseTruncate makes TruncateTables use TRUNCATE TABLE instead of DELETE. It's faster on big
+	// tables, but TRUNCATE commits implicitly, so a failed load no longer rolls the cleanup back.
+	UseTruncate bool
+}
+
+// databaseFor returns the database a fixture schema name refers to
+func (m *MySQLDatabase) databaseFor(schema, current string) string {
+	target, ok := m.SchemaAliases[schema]
+	if !ok && schema == "public" {
+		target, ok = "", true
 	}
 
+	switch {
+	case !ok:
+		return schema
+	case target == "":
+		return current
+	default:
+		return target
+	}
+}
+
+// GetDependencyGraph implements Database.GetDependencyGraph for MySQL
+func (m *MySQLDatabase) GetDependencyGraph(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	// Foreign keys may reference tables of other databases on the same server
 	query := `
-SELECT
-    TABLE_NAME AS child,
-    REFERENCED_TABLE_NAME AS parent
+SELECT DISTINCT
+    CONCAT(TABLE_SCHEMA, '.', TABLE_NAME) AS child,
+    CONCAT(REFERENCED_TABLE_SCHEMA, '.', REFERENCED_TABLE_NAME) AS parent
 FROM
     INFORMATION_SCHEMA.KEY_COLUMN_USAGE
 WHERE
     REFERENCED_TABLE_SCHEMA IS NOT NULL
-    AND TABLE_SCHEMA = ?
-    AND REFERENCED_TABLE_SCHEMA = ?
 `
-	rows, err := db.QueryContext(ctx, query, dbName, dbName)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("query dependencies: %w", err)
 	}
@@ -179,58 +721,158 @@ WHERE
 
 	graph := map[string][]string{}
 	for rows.Next() {
-		var childTable, parentTable string
-		if err := rows.Scan(&childTable, &parentTable); err != nil {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
 			return nil, fmt.Errorf("scan dependency: %w", err)
 		}
 
-		// For compatibility with the fixtures file, we need to add the "public." prefix
-		child := "public." + childTable
-		parent := "public." + parentTable
-
 		graph[child] = append(graph[child], parent)
 	}
 
 	return graph, nil
 }
 
-// TruncateTables implements Database.TruncateTables for MySQL
-func (m *MySQLDatabase) TruncateTables(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error {
-	// MySQL requires foreign key checks to be disabled for truncating tables with foreign key constraints
-	if _, err := tx.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
-		return err
+// GetForeignKeys implements Database.GetForeignKeys for MySQL
+func (m *MySQLDatabase) GetForeignKeys(ctx context.Context, db *sql.DB, tables []string) ([]ForeignKey, error) {
+	query := `
+SELECT
+    CONSTRAINT_NAME,
+    CONCAT(TABLE_SCHEMA, '.', TABLE_NAME),
+    CONCAT(REFERENCED_TABLE_SCHEMA, '.', REFERENCED_TABLE_NAME),
+    COLUMN_NAME,
+    REFERENCED_COLUMN_NAME,
+    FALSE AS DEFERRABLE
+FROM
+    INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+WHERE
+    REFERENCED_TABLE_SCHEMA IS NOT NULL
+ORDER BY
+    TABLE_SCHEMA, TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION
+`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query foreign keys: %w", err)
 	}
+	defer rows.Close()
 
-	for _, schemaTable := range tables {
-		// For MySQL, we need to strip the schema part (if any)
-		parts := strings.Split(schemaTable, ".")
-		tableName := parts[len(parts)-1] // Get the last part (table name)
+	keys, err := scanForeignKeys(rows)
+	if err != nil {
+		return nil, err
+	}
 
-		query := "TRUNCATE TABLE " + tableName
-		if dryRun {
-			log.Println("[dry-run]", query)
-			continue
-		}
+	return filterForeignKeys(keys, tables), nil
+}
 
-		if _, err := tx.ExecContext(ctx, query); err != nil {
-			return err
+// ResolveTables implements Database.ResolveTables for MySQL.
+// Unqualified names belong to the current database, schema names go through SchemaAliases.
+func (m *MySQLDatabase) ResolveTables(ctx context.Context, db *sql.DB, tables []string) (map[string]string, error) {
+	var dbName string
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
+		return nil, fmt.Errorf("get current database: %w", err)
+	}
+
+	resolved := make(map[string]string, len(tables))
+	for _, table := range tables {
+		parts := SplitQualified(table)
+		switch len(parts) {
+		case 1:
+			resolved[table] = dbName + "." + parts[0]
+		case 2:
+			resolved[table] = m.databaseFor(parts[0], dbName) + "." + parts[1]
+		default:
+			resolved[table] = strings.Join(parts, ".")
 		}
 	}
 
-	// Re-enable foreign key checks
-	if _, err := tx.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1"); err != nil {
+	return resolved, nil
+}
+
+// GetColumns implements Database.GetColumns for MySQL
+func (m *MySQLDatabase) GetColumns(ctx context.Context, db *sql.DB, tables []string) (Catalog, error) {
+	query := `
+SELECT
+    CONCAT(TABLE_SCHEMA, '.', TABLE_NAME),
+    COLUMN_NAME,
+    DATA_TYPE,
+    COLUMN_TYPE,
+    IS_NULLABLE = 'YES' AS NULLABLE,
+    COLUMN_DEFAULT IS NOT NULL OR EXTRA LIKE '%auto_increment%' AS HAS_DEFAULT,
+    EXTRA LIKE '%VIRTUAL GENERATED%' OR EXTRA LIKE '%STORED GENERATED%' AS GENERATED,
+    '' AS IDENTITY_GENERATION,
+    COLUMN_KEY = 'PRI' AS PRIMARY_KEY
+FROM
+    INFORMATION_SCHEMA.COLUMNS
+WHERE
+    TABLE_SCHEMA NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
+ORDER BY
+    TABLE_SCHEMA, TABLE_NAME, ORDINAL_POSITION
+`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query columns: %w", err)
+	}
+	defer rows.Close()
+
+	return scanCatalog(rows, tables, func(table string) string { return table })
+}
+
+// TruncateTables implements Database.TruncateTables for MySQL.
+// Tables are cleaned with DELETE, in the given (dependents first) order, so the cleanup is part
+// of the load transaction; see UseTruncate for the non-atomic fast path.
+func (m *MySQLDatabase) TruncateTables(ctx context.Context, q Querier, tables []string, dryRun bool) (err error) {
+	// Foreign key checks are disabled so that self-references and references between the
+	// cleaned tables don't get in the way. The setting belongs to the pooled connection,
+	// so it's restored even if the cleanup fails or is cancelled.
+	if err := m.exec(ctx, q, "SET FOREIGN_KEY_CHECKS = 0", dryRun); err != nil {
 		return err
 	}
+	defer func() {
+		if resetErr := m.exec(context.WithoutCancel(ctx), q, "SET FOREIGN_KEY_CHECKS = 1", dryRun); err == nil {
+			err = resetErr
+		}
+	}()
+
+	for _, schemaTable := range tables {
+		if err := m.exec(ctx, q, m.cleanupQuery(schemaTable), dryRun); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-// InsertRow implements Database.InsertRow for MySQL
-func (m *MySQLDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table string, row map[string]any, dryRun bool) error {
-	// For MySQL, we need to strip the schema part (if any)
-	parts := strings.Split(table, ".")
-	tableName := parts[len(parts)-1] // Get the last part (table name)
+// CleanupStatements implements Database.CleanupStatements for MySQL
+func (m *MySQLDatabase) CleanupStatements(tables []string) []string {
+	statements := []string{"SET FOREIGN_KEY_CHECKS = 0"}
+	for _, table := range tables {
+		statements = append(statements, m.cleanupQuery(table))
+	}
+
+	return append(statements, "SET FOREIGN_KEY_CHECKS = 1")
+}
+
+// cleanupQuery empties one table, see UseTruncate
+func (m *MySQLDatabase) cleanupQuery(table string) string {
+	if m.UseTruncate {
+		return "TRUNCATE TABLE " + quoteQualified(table, m.QuoteIdent)
+	}
+
+	return "DELETE FROM " + quoteQualified(table, m.QuoteIdent)
+}
 
+// exec executes query, or only logs it in dry-run mode
+func (m *MySQLDatabase) exec(ctx context.Context, q Querier, query string, dryRun bool) error {
+	if dryRun {
+		log.Println("[dry-run]", query)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query)
+	return err
+}
+
+// InsertRow implements Database.InsertRow for MySQL
+func (m *MySQLDatabase) InsertRow(ctx context.Context, q Querier, table string, row map[string]any, _ map[string]Column, dryRun bool) error {
 	cols := make([]string, 0, len(row))
 	for col := range row {
 		cols = append(cols, col)
@@ -238,15 +880,17 @@ func (m *MySQLDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table string,
 	sort.Strings(cols)
 
 	vals := make([]any, 0, len(row))
+	quoted := make([]string, 0, len(row))
 	ph := make([]string, 0, len(row))
 	for i, col := range cols {
 		vals = append(vals, row[col])
+		quoted = append(quoted, m.QuoteIdent(col))
 		ph = append(ph, m.Placeholder(i+1))
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName,
-		strings.Join(cols, ", "),
+		quoteQualified(table, m.QuoteIdent),
+		strings.Join(quoted, ", "),
 		strings.Join(ph, ", "),
 	)
 
@@ -255,80 +899,323 @@ func (m *MySQLDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table string,
 		return nil
 	}
 
-	_, err := tx.ExecContext(ctx, query, vals...)
+	_, err := q.ExecContext(ctx, query, vals...)
 	return err
 }
 
-// ResetSequences implements Database.ResetSequences for MySQL
-func (m *MySQLDatabase) ResetSequences(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error {
+// DeferConstraints implements Database.DeferConstraints for MySQL, which checks foreign keys
+// immediately; GetForeignKeys never reports a MySQL key as deferrable
+func (m *MySQLDatabase) DeferConstraints(context.Context, Querier, bool) error {
+	return errors.New("MySQL does not support deferred constraints")
+}
+
+// UpdateRow implements Database.UpdateRow for MySQL
+func (m *MySQLDatabase) UpdateRow(ctx context.Context, q Querier, table string, key, values map[string]any, dryRun bool) error {
+	query, vals := updateQuery(table, key, values, m.QuoteIdent, m.Placeholder)
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query, vals...)
+	return err
+}
+
+// updateQuery builds "UPDATE table SET ... WHERE ..." with the columns of values and key
+// in sorted order, returning the statement and its arguments
+func updateQuery(table string, key, values map[string]any, quote func(string) string, placeholder func(int) string) (string, []any) {
+	vals := make([]any, 0, len(values)+len(key))
+	build := func(m map[string]any) []string {
+		cols := make([]string, 0, len(m))
+		for col := range m {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+
+		parts := make([]string, 0, len(cols))
+		for _, col := range cols {
+			vals = append(vals, m[col])
+			parts = append(parts, quote(col)+" = "+placeholder(len(vals)))
+		}
+
+		return parts
+	}
+
+	set := build(values)
+	where := build(key)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		quoteQualified(table, quote),
+		strings.Join(set, ", "),
+		strings.Join(where, " AND "),
+	)
+
+	return query, vals
+}
+
+// ResetSequences implements Database.ResetSequences for MySQL.
+// ALTER TABLE commits implicitly (see ImplicitCommits), so q must not be a transaction that
+// is still loading: the loader calls this after the rows are committed. All values are read
+// before the first ALTER statement.
+func (m *MySQLDatabase) ResetSequences(ctx context.Context, q Querier, tables []string, dryRun bool) ([]SequenceReset, error) {
 	// MySQL doesn't have sequences like PostgreSQL, but it has AUTO_INCREMENT
 	// We need to get the maximum value for each AUTO_INCREMENT column and set it
+	var alters []string
+	var resets []SequenceReset
 	for _, schemaTable := range tables {
-		parts := strings.Split(schemaTable, ".")
-		var dbName, tableName string
-
-		if len(parts) == 2 {
-			dbName = parts[0]
-			tableName = parts[1]
-		} else if len(parts) == 1 {
-			// If no schema is provided, use the current database
-			dbName = "db" // This is the database name we're using in the test
-			tableName = parts[0]
-		} else {
-			return fmt.Errorf("invalid table name: %q", schemaTable)
-		}
-
-		// Get AUTO_INCREMENT columns for this table
-		query := fmt.Sprintf(`
-SELECT COLUMN_NAME
-FROM INFORMATION_SCHEMA.COLUMNS
-WHERE TABLE_SCHEMA = '%s'
-  AND TABLE_NAME = '%s'
-  AND EXTRA LIKE '%%auto_increment%%'
-`, dbName, tableName)
+		table := quoteQualified(schemaTable, m.QuoteIdent)
 
-		rows, err := tx.QueryContext(ctx, query)
+		columns, err := m.autoIncrementColumns(ctx, q, schemaTable)
 		if err != nil {
-			return fmt.Errorf("query auto_increment columns: %w", err)
+			return nil, err
 		}
 
-		var columns []string
-		for rows.Next() {
-			var column string
-			if err := rows.Scan(&column); err != nil {
-				rows.Close()
-				return fmt.Errorf("scan auto_increment column: %w", err)
-			}
-			columns = append(columns, column)
-		}
-		rows.Close()
-
 		// For each AUTO_INCREMENT column, get the max value and set the AUTO_INCREMENT
 		for _, column := range columns {
 			// Get max value
-			maxQuery := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) + 1 FROM %s", column, tableName)
-			var maxVal int
-			if err := tx.QueryRowContext(ctx, maxQuery).Scan(&maxVal); err != nil {
-				return fmt.Errorf("get max value: %w", err)
+			maxQuery := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) + 1 FROM %s", m.QuoteIdent(column), table)
+			var maxVal int64
+			if err := q.QueryRowContext(ctx, maxQuery).Scan(&maxVal); err != nil {
+				return nil, fmt.Errorf("get max value: %w", err)
 			}
 
-			// Set AUTO_INCREMENT
-			alterQuery := fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", tableName, maxVal)
-			if dryRun {
-				log.Println("[dry-run]", alterQuery)
-				continue
-			}
+			alters = append(alters, fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", table, maxVal))
+			resets = append(resets, SequenceReset{Table: schemaTable, Column: column, Next: maxVal})
+		}
+	}
 
-			if _, err := tx.ExecContext(ctx, alterQuery); err != nil {
-				return fmt.Errorf("set auto_increment: %w", err)
-			}
+	for _, query := range alters {
+		if err := m.exec(ctx, q, query, dryRun); err != nil {
+			return nil, fmt.Errorf("set auto_increment: %w", err)
+		}
+	}
+
+	return resets, nil
+}
+
+// OwnedSequences implements Database.OwnedSequences for MySQL
+func (m *MySQLDatabase) OwnedSequences(ctx context.Context, db *sql.DB, tables []string) ([]SequenceReset, error) {
+	var owned []SequenceReset
+	for _, schemaTable := range tables {
+		columns, err := m.autoIncrementColumns(ctx, db, schemaTable)
+		if err != nil {
+			return nil, err
 		}
+
+		for _, column := range columns {
+			owned = append(owned, SequenceReset{Table: schemaTable, Column: column})
+		}
+	}
+
+	return owned, nil
+}
+
+// autoIncrementColumns returns the AUTO_INCREMENT columns of a db.table
+func (m *MySQLDatabase) autoIncrementColumns(ctx context.Context, q Querier, schemaTable string) ([]string, error) {
+	parts := SplitQualified(schemaTable)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid table name: %q", schemaTable)
+	}
+
+	query := `
+SELECT COLUMN_NAME
+FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = ?
+  AND TABLE_NAME = ?
+  AND EXTRA LIKE '%auto_increment%'
+`
+	rows, err := q.QueryContext(ctx, query, parts[0], parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("query auto_increment columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("scan auto_increment column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// ResolveSequence implements Database.ResolveSequence for MySQL.
+// The name must point to an AUTO_INCREMENT column: table.column or db.table.column.
+func (m *MySQLDatabase) ResolveSequence(ctx context.Context, db *sql.DB, name string) (SequenceReset, error) {
+	var dbName string
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
+		return SequenceReset{}, fmt.Errorf("get current database: %w", err)
+	}
+
+	parts := SplitQualified(name)
+	switch len(parts) {
+	case 2:
+		parts = []string{dbName, parts[0], parts[1]}
+	case 3:
+		parts[0] = m.databaseFor(parts[0], dbName)
+	default:
+		return SequenceReset{}, fmt.Errorf("invalid sequence %q: expected table.column", name)
+	}
+
+	query := `
+SELECT COUNT(*)
+FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = ?
+  AND TABLE_NAME = ?
+  AND COLUMN_NAME = ?
+  AND EXTRA LIKE '%auto_increment%'
+`
+	var found int
+	if err := db.QueryRowContext(ctx, query, parts[0], parts[1], parts[2]).Scan(&found); err != nil {
+		return SequenceReset{}, fmt.Errorf("resolve sequence %q: %w", name, err)
+	}
+	if found == 0 {
+		return SequenceReset{}, fmt.Errorf("unknown sequence %q: not an AUTO_INCREMENT column", name)
+	}
+
+	return SequenceReset{Table: parts[0] + "." + parts[1], Column: parts[2]}, nil
+}
+
+// SetSequence implements Database.SetSequence for MySQL.
+// Like ResetSequences it commits implicitly, so it runs after the rows are committed. InnoDB
+// never sets the counter below MAX(column) + 1.
+func (m *MySQLDatabase) SetSequence(ctx context.Context, q Querier, seq SequenceReset, dryRun bool) error {
+	query := fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", quoteQualified(seq.Table, m.QuoteIdent), seq.Next)
+
+	return m.exec(ctx, q, query, dryRun)
+}
+
+// SetTimeouts implements Database.SetTimeouts for MySQL. MySQL has no SET LOCAL, the session
+// variables outlive the transaction until ResetTimeouts. max_execution_time only limits SELECTs,
+// the lock timeouts are rounded up to whole seconds.
+func (m *MySQLDatabase) SetTimeouts(ctx context.Context, q Querier, timeouts Timeouts, dryRun bool) error {
+	var vars []string
+	if timeouts.Statement > 0 {
+		vars = append(vars, fmt.Sprintf("max_execution_time = %d", millis(timeouts.Statement)))
+	}
+	if timeouts.Lock > 0 {
+		lock := seconds(timeouts.Lock)
+		vars = append(vars, fmt.Sprintf("lock_wait_timeout = %d", lock), fmt.Sprintf("innodb_lock_wait_timeout = %d", lock))
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+
+	return m.exec(ctx, q, "SET SESSION "+strings.Join(vars, ", "), dryRun)
+}
+
+// ResetTimeouts implements Database.ResetTimeouts for MySQL
+func (m *MySQLDatabase) ResetTimeouts(ctx context.Context, q Querier, timeouts Timeouts, dryRun bool) error {
+	var vars []string
+	if timeouts.Statement > 0 {
+		vars = append(vars, "max_execution_time = DEFAULT")
+	}
+	if timeouts.Lock > 0 {
+		vars = append(vars, "lock_wait_timeout = DEFAULT", "innodb_lock_wait_timeout = DEFAULT")
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+
+	return m.exec(ctx, q, "SET SESSION "+strings.Join(vars, ", "), dryRun)
+}
+
+// IsTransient implements Database.IsTransient for MySQL: deadlocks (1213) and lock wait
+// timeouts (1205)
+func (m *MySQLDatabase) IsTransient(err error) bool {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+
+	return myErr.Number == 1213 || myErr.Number == 1205
+}
+
+// Lock implements Database.Lock for MySQL with GET_LOCK, which belongs to the session like
+// the PostgreSQL lock.
+func (m *MySQLDatabase) Lock(ctx context.Context, q Querier, name string, wait time.Duration, dryRun bool) error {
+	// GET_LOCK waits whole seconds, a negative timeout waits forever
+	timeout := int64(-1)
+	if wait > 0 {
+		timeout = seconds(wait)
+	}
+
+	query := "SELECT GET_LOCK(?, ?)"
+	vals := []any{name, timeout}
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	var acquired sql.NullInt64
+	if err := q.QueryRowContext(ctx, query, vals...).Scan(&acquired); err != nil {
+		return err
+	}
+
+	switch {
+	case !acquired.Valid:
+		return errors.New("GET_LOCK failed")
+	case acquired.Int64 == 0:
+		return fmt.Errorf("not acquired within %s", wait)
 	}
 
 	return nil
 }
 
+// Unlock implements Database.Unlock for MySQL
+func (m *MySQLDatabase) Unlock(ctx context.Context, q Querier, name string, dryRun bool) error {
+	query := "SELECT RELEASE_LOCK(?)"
+	vals := []any{name}
+	if dryRun {
+		log.Printf("[dry-run] %s :: %v", query, vals)
+		return nil
+	}
+
+	_, err := q.ExecContext(ctx, query, vals...)
+	return err
+}
+
+// ImplicitCommits implements Database.ImplicitCommits for MySQL: ALTER TABLE always commits,
+// TRUNCATE TABLE is only used with UseTruncate
+func (m *MySQLDatabase) ImplicitCommits() ImplicitCommits {
+	return ImplicitCommits{Cleanup: m.UseTruncate, Sequences: true}
+}
+
 // Placeholder implements Database.Placeholder for MySQL
 func (m *MySQLDatabase) Placeholder(index int) string {
 	return "?"
 }
+
+// QuoteIdent implements Database.QuoteIdent for MySQL
+func (m *MySQLDatabase) QuoteIdent(ident string) string {
+	return quoteMySQL(ident)
+}
+
+// Regular expression to match PostgreSQL interval syntax
+// Example: "INTERVAL '1 day'" -> "INTERVAL 1 DAY"
+var intervalRegex = regexp.MustCompile(`INTERVAL\s+'(\d+)\s+([^']+)'`)
+
+// RewriteExpr implements ExprRewriter for MySQL: it converts PostgreSQL interval syntax to MySQL syntax
+// Example: "SELECT NOW() - INTERVAL '1 day'" -> "SELECT NOW() - INTERVAL 1 DAY"
+func (m *MySQLDatabase) RewriteExpr(expr string) string {
+	return convertIntervalSyntax(expr)
+}
+
+// convertIntervalSyntax converts PostgreSQL interval syntax to MySQL syntax
+func convertIntervalSyntax(expr string) string {
+	return intervalRegex.ReplaceAllStringFunc(expr, func(match string) string {
+		// Extract the number and unit from the interval
+		parts := intervalRegex.FindStringSubmatch(match)
+		if len(parts) != 3 {
+			return match // Return the original if no match
+		}
+
+		number := parts[1]
+		unit := strings.ToUpper(parts[2])
+
+		// Return the MySQL syntax
+		return fmt.Sprintf("INTERVAL %s %s", number, unit)
+	})
+}
@@ -2,9 +2,14 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,16 +25,37 @@ func TestMySQLDatabase_Placeholder(t *testing.T) {
 	require.Equal(t, "?", db.Placeholder(2))
 }
 
+func TestPostgresDatabase_QuoteIdent(t *testing.T) {
+	db := &PostgresDatabase{}
+	require.Equal(t, `"order"`, db.QuoteIdent("order"))
+	require.Equal(t, `"createdAt"`, db.QuoteIdent("createdAt"))
+	require.Equal(t, `"a""b"`, db.QuoteIdent(`a"b`))
+}
+
+func TestMySQLDatabase_QuoteIdent(t *testing.T) {
+	db := &MySQLDatabase{}
+	require.Equal(t, "`order`", db.QuoteIdent("order"))
+	require.Equal(t, "`a``b`", db.QuoteIdent("a`b"))
+}
+
 func TestPostgresDatabase_GetDependencyGraph(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
 	require.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT tc.table_schema").WillReturnRows(
-		sqlmock.NewRows([]string{"child", "parent"}).
-			AddRow("public.orders", "public.users").
-			AddRow("public.orders2products", "public.orders").
-			AddRow("public.orders2products", "public.products"),
+	mock.ExpectQuery("FROM\\s+pg_constraint").WillReturnRows(
+		sqlmock.NewRows([]string{"conname", "table", "ref_table", "column", "ref_column", "condeferrable"}).
+			// Composite key: one row per column, one edge
+			AddRow("items_order_fk", "public.items", "public.orders", "tenant_id", "tenant_id", false).
+			AddRow("items_order_fk", "public.items", "public.orders", "order_id", "id", false).
+			// Cross-schema reference
+			AddRow("orders_user_fk", "public.orders", "auth.users", "user_id", "id", false).
+			// Constraint of a partitioned table, copied to its partition and to the
+			// partitions of the referenced table
+			AddRow("events_user_fk", "public.events", "auth.users", "user_id", "id", false).
+			AddRow("events_user_fk", "public.events_2024", "auth.users", "user_id", "id", false).
+			AddRow("comments_event_fk", "public.comments", "public.events", "event_id", "id", false).
+			AddRow("comments_event_fk", "public.comments", "public.events_2024", "event_id", "id", false),
 	)
 
 	d := &PostgresDatabase{}
@@ -37,8 +63,11 @@ func TestPostgresDatabase_GetDependencyGraph(t *testing.T) {
 	graph, err := d.GetDependencyGraph(ctx, db)
 	require.NoError(t, err)
 	require.Equal(t, map[string][]string{
-		"public.orders":          {"public.users"},
-		"public.orders2products": {"public.orders", "public.products"},
+		"public.items":       {"public.orders"},
+		"public.orders":      {"auth.users"},
+		"public.events":      {"auth.users"},
+		"public.events_2024": {"auth.users"},
+		"public.comments":    {"public.events", "public.events_2024"},
 	}, graph)
 
 	require.NoError(t, mock.ExpectationsWereMet())
@@ -49,16 +78,12 @@ func TestMySQLDatabase_GetDependencyGraph(t *testing.T) {
 	require.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(
-		sqlmock.NewRows([]string{"DATABASE()"}).AddRow("testdb"),
-	)
-	mock.ExpectQuery("SELECT\\s+TABLE_NAME AS child,\\s+REFERENCED_TABLE_NAME AS parent").
-		WithArgs("testdb", "testdb").
+	mock.ExpectQuery("FROM\\s+INFORMATION_SCHEMA.KEY_COLUMN_USAGE").
 		WillReturnRows(
 			sqlmock.NewRows([]string{"child", "parent"}).
-				AddRow("orders", "users").
-				AddRow("orders2products", "orders").
-				AddRow("orders2products", "products"),
+				AddRow("testdb.orders", "testdb.users").
+				AddRow("testdb.orders2products", "testdb.orders").
+				AddRow("testdb.orders2products", "catalog.products"),
 		)
 
 	d := &MySQLDatabase{}
@@ -66,12 +91,87 @@ func TestMySQLDatabase_GetDependencyGraph(t *testing.T) {
 	graph, err := d.GetDependencyGraph(ctx, db)
 	require.NoError(t, err)
 	require.Equal(t, map[string][]string{
-		"public.orders":          {"public.users"},
-		"public.orders2products": {"public.orders", "public.products"},
+		"testdb.orders":          {"testdb.users"},
+		"testdb.orders2products": {"testdb.orders", "catalog.products"},
 	}, graph)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestPostgresDatabase_ResolveTables(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("to_regclass").WithArgs(`"users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("public.users"))
+	mock.ExpectQuery("to_regclass").WithArgs(`"public"."users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("public.users"))
+	mock.ExpectQuery("to_regclass").WithArgs(`"missing"`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	d := &PostgresDatabase{}
+	resolved, err := d.ResolveTables(context.Background(), db, []string{"users", `"public"."users"`, "missing"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"users":            "public.users",
+		`"public"."users"`: "public.users",
+		"missing":          "missing",
+	}, resolved)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_ResolveTables(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(
+		sqlmock.NewRows([]string{"DATABASE()"}).AddRow("testdb"),
+	)
+
+	d := &MySQLDatabase{}
+	resolved, err := d.ResolveTables(context.Background(), db, []string{"users", "testdb.orders", "public.items", "other.logs"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"users":         "testdb.users",
+		"testdb.orders": "testdb.orders",
+		"public.items":  "testdb.items",
+		"other.logs":    "other.logs",
+	}, resolved)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_ResolveTables_SchemaAliases(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(
+		sqlmock.NewRows([]string{"DATABASE()"}).AddRow("testdb"),
+	)
+
+	d := &MySQLDatabase{SchemaAliases: map[string]string{"billing": "billing_test", "app": ""}}
+	resolved, err := d.ResolveTables(context.Background(), db, []string{"billing.invoices", "app.users", "public.items"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"billing.invoices": "billing_test.invoices",
+		"app.users":        "testdb.users",
+		// The default alias is kept next to the configured ones
+		"public.items": "testdb.items",
+	}, resolved)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Unless public is mapped explicitly
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(
+		sqlmock.NewRows([]string{"DATABASE()"}).AddRow("testdb"),
+	)
+	d.SchemaAliases["public"] = "public"
+	resolved, err = d.ResolveTables(context.Background(), db, []string{"public.items"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"public.items": "public.items"}, resolved)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPostgresDatabase_TruncateTables(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -89,7 +189,7 @@ func TestPostgresDatabase_TruncateTables(t *testing.T) {
 	require.NoError(t, err)
 
 	// dryRun = false (will TRUNCATE)
-	mock.ExpectExec("TRUNCATE public.orders, public.users RESTART IDENTITY CASCADE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`TRUNCATE "public"."orders", "public"."users" RESTART IDENTITY CASCADE`).WillReturnResult(sqlmock.NewResult(0, 0))
 	err = database.TruncateTables(context.Background(), tx, tables, false)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
@@ -105,27 +205,82 @@ func TestMySQLDatabase_TruncateTables(t *testing.T) {
 	require.NoError(t, err)
 
 	database := &MySQLDatabase{}
-	tables := []string{"public.orders", "public.users"}
+	tables := []string{"testdb.orders", "otherdb.users"}
+
+	// dryRun = true (nothing is executed, not even SET FOREIGN_KEY_CHECKS)
+	err = database.TruncateTables(context.Background(), tx, tables, true)
+	require.NoError(t, err)
 
-	// dryRun = true (nothing do, only SET FOREIGN_KEY_CHECKS)
+	// dryRun = false (DELETE inside the transaction)
 	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `testdb`.`orders`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `otherdb`.`users`").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 1").WillReturnResult(sqlmock.NewResult(0, 0))
-	err = database.TruncateTables(context.Background(), tx, tables, true)
+	err = database.TruncateTables(context.Background(), tx, tables, false)
 	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_TruncateTables_Cancelled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The context ends during the DELETE, foreign key checks are turned back on anyway
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `testdb`.`orders`").WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	database := &MySQLDatabase{}
+	err = database.TruncateTables(ctx, conn, []string{"testdb.orders"}, false)
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_TruncateTables_UseTruncate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
 
-	// dryRun = false (will TRUNCATE)
 	mock.ExpectBegin()
-	tx2, err := db.Begin()
+	tx, err := db.Begin()
 	require.NoError(t, err)
+
 	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 0").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("TRUNCATE TABLE orders").WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("TRUNCATE TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("TRUNCATE TABLE `testdb`.`orders`").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 1").WillReturnResult(sqlmock.NewResult(0, 0))
-	err = database.TruncateTables(context.Background(), tx2, tables, false)
+
+	database := &MySQLDatabase{UseTruncate: true}
+	err = database.TruncateTables(context.Background(), tx, []string{"testdb.orders"}, false)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestMySQLDatabase_TruncateTables_RestoresForeignKeyChecks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `testdb`.`orders`").WillReturnError(errors.New("lock wait timeout"))
+	mock.ExpectExec("SET FOREIGN_KEY_CHECKS = 1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	database := &MySQLDatabase{}
+	err = database.TruncateTables(context.Background(), tx, []string{"testdb.orders", "testdb.users"}, false)
+	require.EqualError(t, err, "lock wait timeout")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPostgresDatabase_InsertRow(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -139,14 +294,45 @@ func TestPostgresDatabase_InsertRow(t *testing.T) {
 	row := map[string]any{"id": 1, "name": "test"}
 
 	// dryRun = true
-	err = database.InsertRow(context.Background(), tx, "public.users", row, true)
+	err = database.InsertRow(context.Background(), tx, "public.users", row, nil, true)
 	require.NoError(t, err)
 
 	// dryRun = false
-	mock.ExpectExec("INSERT INTO public.users \\(id, name\\) VALUES \\(\\$1, \\$2\\)").
+	mock.ExpectExec(`INSERT INTO "public"."users" \("id", "name"\) VALUES \(\$1, \$2\)`).
 		WithArgs(1, "test").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	err = database.InsertRow(context.Background(), tx, "public.users", row, false)
+	err = database.InsertRow(context.Background(), tx, "public.users", row, nil, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_InsertRow_IdentityAlways(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	database := &PostgresDatabase{}
+	columns := map[string]Column{
+		"id":   {Name: "id", DataType: "integer", HasDefault: true, Identity: "ALWAYS"},
+		"code": {Name: "code", DataType: "integer", HasDefault: true, Identity: "BY DEFAULT"},
+	}
+
+	// An explicit value for a GENERATED ALWAYS identity column needs OVERRIDING SYSTEM VALUE
+	mock.ExpectExec(`INSERT INTO "public"."users" \("id", "name"\) OVERRIDING SYSTEM VALUE VALUES \(\$1, \$2\)`).
+		WithArgs(1, "test").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	err = database.InsertRow(context.Background(), tx, "public.users", map[string]any{"id": 1, "name": "test"}, columns, false)
+	require.NoError(t, err)
+
+	// BY DEFAULT identity columns accept values as is
+	mock.ExpectExec(`INSERT INTO "public"."users" \("code", "name"\) VALUES \(\$1, \$2\)`).
+		WithArgs(7, "test").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	err = database.InsertRow(context.Background(), tx, "public.users", map[string]any{"code": 7, "name": "test"}, columns, false)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -164,14 +350,589 @@ func TestMySQLDatabase_InsertRow(t *testing.T) {
 	row := map[string]any{"id": 1, "name": "test"}
 
 	// dryRun = true
-	err = database.InsertRow(context.Background(), tx, "public.users", row, true)
+	err = database.InsertRow(context.Background(), tx, "testdb.users", row, nil, true)
 	require.NoError(t, err)
 
 	// dryRun = false
-	mock.ExpectExec("INSERT INTO users \\(id, name\\) VALUES \\(\\?, \\?\\)").
+	mock.ExpectExec("INSERT INTO `testdb`.`users` \\(`id`, `name`\\) VALUES \\(\\?, \\?\\)").
 		WithArgs(1, "test").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	err = database.InsertRow(context.Background(), tx, "public.users", row, false)
+	err = database.InsertRow(context.Background(), tx, "testdb.users", row, nil, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_UpdateRow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	database := &PostgresDatabase{}
+	mock.ExpectExec(`UPDATE "public"."users" SET "last_order_id" = \$1 WHERE "id" = \$2 AND "tenant_id" = \$3`).
+		WithArgs(10, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = database.UpdateRow(context.Background(), tx, "public.users",
+		map[string]any{"tenant_id": 2, "id": 1}, map[string]any{"last_order_id": 10}, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_DeferConstraints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	database := &PostgresDatabase{}
+	require.NoError(t, database.DeferConstraints(context.Background(), tx, true))
+
+	mock.ExpectExec("SET CONSTRAINTS ALL DEFERRED").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, database.DeferConstraints(context.Background(), tx, false))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_SetTimeouts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	database := &PostgresDatabase{}
+	timeouts := Timeouts{Statement: 1500 * time.Millisecond, Lock: 2 * time.Second}
+
+	// In a transaction the settings end with it
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	mock.ExpectExec("SET LOCAL statement_timeout = 1500").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET LOCAL lock_timeout = 2000").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, database.SetTimeouts(context.Background(), tx, timeouts, false))
+	require.NoError(t, database.ResetTimeouts(context.Background(), tx, timeouts, false))
+
+	// On a connection they are set for the session and reset afterwards
+	mock.ExpectExec("SET lock_timeout = 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RESET lock_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, database.SetTimeouts(context.Background(), db, Timeouts{Lock: time.Microsecond}, false))
+	require.NoError(t, database.ResetTimeouts(context.Background(), db, Timeouts{Lock: time.Microsecond}, false))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_SetTimeouts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	database := &MySQLDatabase{}
+	timeouts := Timeouts{Statement: 1500 * time.Millisecond, Lock: 1500 * time.Millisecond}
+
+	mock.ExpectExec("SET SESSION max_execution_time = 1500, lock_wait_timeout = 2, innodb_lock_wait_timeout = 2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET SESSION max_execution_time = DEFAULT, lock_wait_timeout = DEFAULT, innodb_lock_wait_timeout = DEFAULT").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, database.SetTimeouts(context.Background(), db, timeouts, false))
+	require.NoError(t, database.ResetTimeouts(context.Background(), db, timeouts, false))
+
+	// Nothing to do without limits
+	require.NoError(t, database.SetTimeouts(context.Background(), db, Timeouts{}, false))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDatabase_IsTransient(t *testing.T) {
+	tests := []struct {
+		name     string
+		database Database
+		err      error
+		want     bool
+	}{
+		{name: "pg serialization failure", database: &PostgresDatabase{}, err: &pq.Error{Code: "40001"}, want: true},
+		{name: "pg deadlock, wrapped", database: &PostgresDatabase{}, err: fmt.Errorf("insert: %w", &pq.Error{Code: "40P01"}), want: true},
+		{name: "pg unique violation", database: &PostgresDatabase{}, err: &pq.Error{Code: "23505"}},
+		{name: "pg other error", database: &PostgresDatabase{}, err: errors.New("connection refused")},
+		{name: "mysql deadlock", database: &MySQLDatabase{}, err: &mysql.MySQLError{Number: 1213}, want: true},
+		{name: "mysql lock wait timeout", database: &MySQLDatabase{}, err: fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1205}), want: true},
+		{name: "mysql duplicate entry", database: &MySQLDatabase{}, err: &mysql.MySQLError{Number: 1062}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.database.IsTransient(tt.err))
+		})
+	}
+}
+
+func TestPostgresDatabase_Lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	database := &PostgresDatabase{}
+	mock.ExpectExec("SET lock_timeout = 2000").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_lock\\(hashtext\\(\\$1\\)\\)").
+		WithArgs("fixtures").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RESET lock_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, database.Lock(context.Background(), conn, "fixtures", 2*time.Second, false))
+
+	// Without a wait no timeout is set, the lock times out like any other
+	mock.ExpectExec("SELECT pg_advisory_lock\\(hashtext\\(\\$1\\)\\)").
+		WithArgs("fixtures").
+		WillReturnError(&pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"})
+	err = database.Lock(context.Background(), conn, "fixtures", 0, false)
+	require.EqualError(t, err, "not acquired within 0s: pq: canceling statement due to lock timeout")
+
+	mock.ExpectExec("SELECT pg_advisory_unlock\\(hashtext\\(\\$1\\)\\)").
+		WithArgs("fixtures").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, database.Unlock(context.Background(), conn, "fixtures", false))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_Lock(t *testing.T) {
+	tests := []struct {
+		name     string
+		wait     time.Duration
+		timeout  int64
+		acquired any
+		wantErr  string
+	}{
+		{name: "acquired", wait: 1500 * time.Millisecond, timeout: 2, acquired: 1},
+		{name: "forever", timeout: -1, acquired: 1},
+		{name: "timed out", wait: time.Second, timeout: 1, acquired: 0, wantErr: "not acquired within 1s"},
+		{name: "failed", wait: time.Second, timeout: 1, acquired: nil, wantErr: "GET_LOCK failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery("SELECT GET_LOCK\\(\\?, \\?\\)").
+				WithArgs("fixtures", tt.timeout).
+				WillReturnRows(sqlmock.NewRows([]string{"GET_LOCK"}).AddRow(tt.acquired))
+
+			database := &MySQLDatabase{}
+			err = database.Lock(context.Background(), db, "fixtures", tt.wait, false)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMySQLDatabase_Unlock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("SELECT RELEASE_LOCK\\(\\?\\)").WithArgs("fixtures").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	database := &MySQLDatabase{}
+	require.NoError(t, database.Unlock(context.Background(), db, "fixtures", false))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_UpdateRow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	database := &MySQLDatabase{}
+	mock.ExpectExec("UPDATE `testdb`.`users` SET `last_order_id` = \\? WHERE `id` = \\?").
+		WithArgs(10, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = database.UpdateRow(context.Background(), tx, "testdb.users",
+		map[string]any{"id": 1}, map[string]any{"last_order_id": 10}, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_ResetSequences(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	// Serial column with rows: the next value follows the maximum
+	mock.ExpectQuery("FROM\\s+pg_depend").WithArgs(`"public"."users"`).WillReturnRows(
+		sqlmock.NewRows([]string{"seq", "attname", "seqstart", "seqincrement"}).
+			AddRow("users_id_seq", "id", 1, 1),
+	)
+	mock.ExpectQuery(`SELECT MAX\("id"\) FROM "public"."users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(3))
+	mock.ExpectExec(`SELECT setval\(\$1::regclass, \$2, \$3\)`).
+		WithArgs("users_id_seq", int64(3), true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Identity column of an empty table: the sequence restarts at its start value
+	mock.ExpectQuery("FROM\\s+pg_depend").WithArgs(`"public"."Orders"`).WillReturnRows(
+		sqlmock.NewRows([]string{"seq", "attname", "seqstart", "seqincrement"}).
+			AddRow(`"Orders_orderId_seq"`, "orderId", 100, 1),
+	)
+	mock.ExpectQuery(`SELECT MAX\("orderId"\) FROM "public"."Orders"`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectExec(`SELECT setval\(\$1::regclass, \$2, \$3\)`).
+		WithArgs(`"Orders_orderId_seq"`, int64(100), false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Table without sequences
+	mock.ExpectQuery("FROM\\s+pg_depend").WithArgs(`"public"."tags"`).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "attname", "seqstart", "seqincrement"}))
+
+	database := &PostgresDatabase{}
+	resets, err := database.ResetSequences(context.Background(), tx, []string{"public.users", "public.Orders", "public.tags"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []SequenceReset{
+		{Table: "public.users", Column: "id", Sequence: "users_id_seq", Next: 4},
+		{Table: "public.Orders", Column: "orderId", Sequence: `"Orders_orderId_seq"`, Next: 100},
+	}, resets)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_OwnedSequences(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Only the catalog is read, no table and no sequence value
+	mock.ExpectQuery("FROM\\s+pg_depend").WithArgs(`"public"."users"`).WillReturnRows(
+		sqlmock.NewRows([]string{"seq", "attname", "seqstart", "seqincrement"}).
+			AddRow("users_id_seq", "id", 1, 1),
+	)
+	mock.ExpectQuery("FROM\\s+pg_depend").WithArgs(`"public"."tags"`).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "attname", "seqstart", "seqincrement"}))
+
+	database := &PostgresDatabase{}
+	owned, err := database.OwnedSequences(context.Background(), db, []string{"public.users", "public.tags"})
+	require.NoError(t, err)
+	require.Equal(t, []SequenceReset{{Table: "public.users", Column: "id", Sequence: "users_id_seq"}}, owned)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_ResetSequences_DryRun(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	// Descending sequence: continues below the smallest value; setval is only logged
+	mock.ExpectQuery("FROM\\s+pg_depend").WillReturnRows(
+		sqlmock.NewRows([]string{"seq", "attname", "seqstart", "seqincrement"}).
+			AddRow("events_id_seq", "id", -1, -1),
+	)
+	mock.ExpectQuery(`SELECT MIN\("id"\) FROM "public"."events"`).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(-10))
+
+	database := &PostgresDatabase{}
+	resets, err := database.ResetSequences(context.Background(), tx, []string{"public.events"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []SequenceReset{
+		{Table: "public.events", Column: "id", Sequence: "events_id_seq", Next: -11},
+	}, resets)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_ResolveSequence(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// By sequence name
+	mock.ExpectQuery("FROM\\s+pg_class s\\s+LEFT JOIN pg_depend").WithArgs(`"public"."users_id_seq"`).WillReturnRows(
+		sqlmock.NewRows([]string{"seq", "table", "column"}).AddRow("users_id_seq", "public.users", "id"),
+	)
+	// By column: to_regclass('"public"."users"."id"') fails with "cross-database references are
+	// not implemented", so three parts go straight to the owned sequence of public.users.id
+	mock.ExpectQuery("FROM\\s+pg_depend d").WithArgs(`"public"."users"`, "id").WillReturnRows(
+		sqlmock.NewRows([]string{"seq", "table"}).AddRow("users_id_seq", "public.users"),
+	)
+	// Neither
+	mock.ExpectQuery("FROM\\s+pg_class s\\s+LEFT JOIN pg_depend").WithArgs(`"users"."name"`).
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "table", "column"}))
+	mock.ExpectQuery("FROM\\s+pg_depend d").WithArgs(`"users"`, "name").
+		WillReturnRows(sqlmock.NewRows([]string{"seq", "table"}))
+
+	d := &PostgresDatabase{}
+	expected := SequenceReset{Table: "public.users", Column: "id", Sequence: "users_id_seq"}
+
+	seq, err := d.ResolveSequence(context.Background(), db, "public.users_id_seq")
+	require.NoError(t, err)
+	require.Equal(t, expected, seq)
+
+	seq, err = d.ResolveSequence(context.Background(), db, "public.users.id")
+	require.NoError(t, err)
+	require.Equal(t, expected, seq)
+
+	_, err = d.ResolveSequence(context.Background(), db, "users.name")
+	require.EqualError(t, err, `unknown sequence "users.name": neither a sequence nor a serial or identity column`)
+
+	_, err = d.ResolveSequence(context.Background(), db, "app.public.users.id")
+	require.EqualError(t, err, `invalid sequence "app.public.users.id": expected a sequence or table.column`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_SetSequence(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	mock.ExpectExec(`SELECT setval\(\$1::regclass, \$2, false\)`).WithArgs("users_id_seq", int64(1000)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	d := &PostgresDatabase{}
+	err = d.SetSequence(context.Background(), tx, SequenceReset{Sequence: "users_id_seq", Next: 1000}, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_ResolveSequence(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(sqlmock.NewRows([]string{"DATABASE()"}).AddRow("testdb"))
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("testdb", "users", "id").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(sqlmock.NewRows([]string{"DATABASE()"}).AddRow("testdb"))
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("testdb", "users", "name").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(0))
+
+	d := &MySQLDatabase{}
+	seq, err := d.ResolveSequence(context.Background(), db, "public.users.id")
+	require.NoError(t, err)
+	require.Equal(t, SequenceReset{Table: "testdb.users", Column: "id"}, seq)
+
+	_, err = d.ResolveSequence(context.Background(), db, "users.name")
+	require.EqualError(t, err, `unknown sequence "users.name": not an AUTO_INCREMENT column`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_SetSequence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	mock.ExpectExec("ALTER TABLE `testdb`.`users` AUTO_INCREMENT = 1000").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	d := &MySQLDatabase{}
+	err = d.SetSequence(context.Background(), tx, SequenceReset{Table: "testdb.users", Column: "id", Next: 1000}, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_ResetSequences(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("otherdb", "users").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(`id`\\), 0\\) \\+ 1 FROM `otherdb`.`users`").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(4))
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("otherdb", "orders").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(`id`\\), 0\\) \\+ 1 FROM `otherdb`.`orders`").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(1))
+
+	// ALTER TABLE commits implicitly, so it only runs after every value has been read
+	mock.ExpectExec("ALTER TABLE `otherdb`.`users` AUTO_INCREMENT = 4").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE `otherdb`.`orders` AUTO_INCREMENT = 1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	database := &MySQLDatabase{}
+	resets, err := database.ResetSequences(context.Background(), tx, []string{"otherdb.users", "otherdb.orders"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []SequenceReset{
+		{Table: "otherdb.users", Column: "id", Next: 4},
+		{Table: "otherdb.orders", Column: "id", Next: 1},
+	}, resets)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_OwnedSequences(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM INFORMATION_SCHEMA.COLUMNS").WithArgs("otherdb", "users").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("id"))
+
+	database := &MySQLDatabase{}
+	owned, err := database.OwnedSequences(context.Background(), db, []string{"otherdb.users"})
+	require.NoError(t, err)
+	require.Equal(t, []SequenceReset{{Table: "otherdb.users", Column: "id"}}, owned)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConvertIntervalSyntax(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected string
+	}{
+		{
+			name:     "single day",
+			expr:     "SELECT NOW() - INTERVAL '1 day'",
+			expected: "SELECT NOW() - INTERVAL 1 DAY",
+		},
+		{
+			name:     "multiple months",
+			expr:     "INTERVAL '3 month'",
+			expected: "INTERVAL 3 MONTH",
+		},
+		{
+			name:     "no interval",
+			expr:     "SELECT NOW()",
+			expected: "SELECT NOW()",
+		},
+		{
+			name:     "invalid interval format",
+			expr:     "INTERVAL '1day'",
+			expected: "INTERVAL '1day'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := convertIntervalSyntax(tt.expr)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPostgresDatabase_GetColumns(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("pg_constraint pk(.|\\s)+contype = 'p'(.|\\s)+FROM\\s+information_schema.columns").WillReturnRows(
+		sqlmock.NewRows([]string{"table_name", "column_name", "data_type", "udt_name", "nullable", "has_default", "generated", "identity", "primary_key"}).
+			AddRow("public.users", "id", "integer", "int4", false, true, false, "ALWAYS", true).
+			AddRow("public.users", "meta", "jsonb", "jsonb", true, false, false, "", false).
+			AddRow("public.users", "tags", "ARRAY", "_text", false, false, false, "", false).
+			AddRow("public.users", "slug", "text", "text", true, false, true, "", false).
+			AddRow("public.other", "id", "integer", "int4", false, true, false, "BY DEFAULT", true),
+	)
+
+	d := &PostgresDatabase{}
+	catalog, err := d.GetColumns(context.Background(), db, []string{"public.users"})
+	require.NoError(t, err)
+	require.Equal(t, Catalog{
+		"public.users": {
+			"id":   {Name: "id", DataType: "integer", UDTName: "int4", HasDefault: true, Identity: "ALWAYS", PrimaryKey: true},
+			"meta": {Name: "meta", DataType: "jsonb", UDTName: "jsonb", Nullable: true},
+			"tags": {Name: "tags", DataType: "ARRAY", UDTName: "_text"},
+			"slug": {Name: "slug", DataType: "text", UDTName: "text", Nullable: true, Generated: true},
+		},
+	}, catalog)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_GetColumns(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM\\s+INFORMATION_SCHEMA.COLUMNS").WillReturnRows(
+		sqlmock.NewRows([]string{"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "COLUMN_TYPE", "NULLABLE", "HAS_DEFAULT", "GENERATED", "IDENTITY_GENERATION", "PRIMARY_KEY"}).
+			AddRow("testdb.users", "id", "int", "int", 0, 1, 0, "", 1).
+			AddRow("testdb.users", "meta", "json", "json", 1, 0, 0, "", 0).
+			AddRow("otherdb.users", "id", "int", "int", 0, 1, 0, "", 1),
+	)
+
+	d := &MySQLDatabase{}
+	catalog, err := d.GetColumns(context.Background(), db, []string{"testdb.users"})
+	require.NoError(t, err)
+	require.Equal(t, Catalog{
+		"testdb.users": {
+			"id":   {Name: "id", DataType: "int", UDTName: "int", HasDefault: true, PrimaryKey: true},
+			"meta": {Name: "meta", DataType: "json", UDTName: "json", Nullable: true},
+		},
+	}, catalog)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDatabase_GetForeignKeys(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM\\s+pg_constraint").WillReturnRows(
+		sqlmock.NewRows([]string{"conname", "table", "ref_table", "column", "ref_column", "condeferrable"}).
+			AddRow("categories_parent_fk", "public.categories", "public.categories", "parent_id", "id", false).
+			AddRow("comments_event_fk", "public.comments", "public.events", "event_id", "id", false).
+			AddRow("comments_event_fk", "public.comments", "public.events_2024", "event_id", "id", false).
+			AddRow("items_order_fk", "public.items", "public.orders", "tenant_id", "tenant_id", true).
+			AddRow("items_order_fk", "public.items", "public.orders", "order_id", "id", true).
+			AddRow("items_product_fk", "public.items", "public.products", "product_id", "id", false).
+			AddRow("orders_user_fk", "public.orders", "public.users", "user_id", "id", false),
+	)
+
+	d := &PostgresDatabase{}
+	keys, err := d.GetForeignKeys(context.Background(), db, []string{"public.categories", "public.comments", "public.items"})
+	require.NoError(t, err)
+	require.Equal(t, []ForeignKey{
+		{Name: "categories_parent_fk", Table: "public.categories", Columns: []string{"parent_id"}, RefTable: "public.categories", RefColumns: []string{"id"}},
+		{Name: "comments_event_fk", Table: "public.comments", Columns: []string{"event_id"}, RefTable: "public.events", RefColumns: []string{"id"}},
+		{Name: "comments_event_fk", Table: "public.comments", Columns: []string{"event_id"}, RefTable: "public.events_2024", RefColumns: []string{"id"}},
+		{Name: "items_order_fk", Table: "public.items", Columns: []string{"tenant_id", "order_id"}, RefTable: "public.orders", RefColumns: []string{"tenant_id", "id"}, Deferrable: true},
+		{Name: "items_product_fk", Table: "public.items", Columns: []string{"product_id"}, RefTable: "public.products", RefColumns: []string{"id"}},
+	}, keys)
+	require.True(t, keys[0].IsSelfReference())
+	require.False(t, keys[1].IsSelfReference())
+	require.Len(t, keys[3].Columns, 2)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDatabase_GetForeignKeys(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM\\s+INFORMATION_SCHEMA.KEY_COLUMN_USAGE").WillReturnRows(
+		sqlmock.NewRows([]string{"CONSTRAINT_NAME", "table", "ref_table", "COLUMN_NAME", "REFERENCED_COLUMN_NAME", "DEFERRABLE"}).
+			AddRow("fk_parent", "testdb.categories", "testdb.categories", "parent_id", "id", 0),
+	)
+
+	d := &MySQLDatabase{}
+	keys, err := d.GetForeignKeys(context.Background(), db, []string{"testdb.categories"})
 	require.NoError(t, err)
+	require.Equal(t, []ForeignKey{
+		{Name: "fk_parent", Table: "testdb.categories", Columns: []string{"parent_id"}, RefTable: "testdb.categories", RefColumns: []string{"id"}},
+	}, keys)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// ForeignKey describes a foreign key constraint.
+// Columns and RefColumns are in matching order, so Columns[i] references RefColumns[i].
+type ForeignKey struct {
+	Name       string
+	Table      string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	// Deferrable is set for PostgreSQL DEFERRABLE constraints, checkable at commit time
+	Deferrable bool
+}
+
+// IsSelfReference reports whether the key references its own table
+func (fk ForeignKey) IsSelfReference() bool {
+	return fk.Table == fk.RefTable
+}
+
+// scanForeignKeys reads one row per key column (name, table, referenced table, column,
+// referenced column, deferrable), ordered by table, constraint, referenced table and position
+func scanForeignKeys(rows *sql.Rows) ([]ForeignKey, error) {
+	var keys []ForeignKey
+	for rows.Next() {
+		var name, table, refTable, column, refColumn string
+		var deferrable bool
+		if err := rows.Scan(&name, &table, &refTable, &column, &refColumn, &deferrable); err != nil {
+			return nil, fmt.Errorf("scan foreign key: %w", err)
+		}
+
+		// Partitions of a referenced table carry constraints of the same name
+		if n := len(keys); n > 0 && keys[n-1].Name == name && keys[n-1].Table == table && keys[n-1].RefTable == refTable {
+			keys[n-1].Columns = append(keys[n-1].Columns, column)
+			keys[n-1].RefColumns = append(keys[n-1].RefColumns, refColumn)
+			continue
+		}
+
+		keys = append(keys, ForeignKey{
+			Name:       name,
+			Table:      table,
+			Columns:    []string{column},
+			RefTable:   refTable,
+			RefColumns: []string{refColumn},
+			Deferrable: deferrable,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read foreign keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// filterForeignKeys keeps the keys of the given tables
+func filterForeignKeys(keys []ForeignKey, tables []string) []ForeignKey {
+	wanted := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		wanted[t] = true
+	}
+
+	var filtered []ForeignKey
+	for _, fk := range keys {
+		if wanted[fk.Table] {
+			filtered = append(filtered, fk)
+		}
+	}
+
+	return filtered
+}
+
+// dependencyGraph maps every table to the tables its foreign keys reference, once each
+// however many keys or key columns there are
+func dependencyGraph(keys []ForeignKey) map[string][]string {
+	graph := map[string][]string{}
+	for _, fk := range keys {
+		if !slices.Contains(graph[fk.Table], fk.RefTable) {
+			graph[fk.Table] = append(graph[fk.Table], fk.RefTable)
+		}
+	}
+
+	return graph
+}
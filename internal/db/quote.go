@@ -0,0 +1,57 @@
+package db
+
+import (
+	"strings"
+)
+
+// SplitQualified splits a dot-separated name into its parts, e.g. public.users,
+// "public"."Users" or `db`.`users`. Quoted parts may contain dots and doubled quotes.
+func SplitQualified(name string) []string {
+	var parts []string
+	var cur strings.Builder
+	var quote rune
+	runes := []rune(name)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			if i+1 < len(runes) && runes[i+1] == quote {
+				cur.WriteRune(r)
+				i++
+			} else {
+				quote = 0
+			}
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '"' || r == '`':
+			quote = r
+		case r == '.':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+
+	return append(parts, cur.String())
+}
+
+// quotePostgres quotes a PostgreSQL identifier: users -> "users", a"b -> "a""b"
+func quotePostgres(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// quoteMySQL quotes a MySQL identifier: order -> `order`
+func quoteMySQL(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+}
+
+// quoteQualified quotes every part of a dot-separated name
+func quoteQualified(name string, quote func(string) string) string {
+	parts := SplitQualified(name)
+	for i, part := range parts {
+		parts[i] = quote(part)
+	}
+
+	return strings.Join(parts, ".")
+}
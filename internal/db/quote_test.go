@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitQualified(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"plain", "users", []string{"users"}},
+		{"schema and table", "public.users", []string{"public", "users"}},
+		{"double quoted", `"public"."UserAccounts"`, []string{"public", "UserAccounts"}},
+		{"backticks", "`db`.`order`", []string{"db", "order"}},
+		{"dot inside quotes", `public."a.b"`, []string{"public", "a.b"}},
+		{"doubled quote", `"a""b"`, []string{`a"b`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, SplitQualified(tt.input))
+		})
+	}
+}
+
+func TestQuoteQualified(t *testing.T) {
+	require.Equal(t, `"public"."order"`, quoteQualified("public.order", quotePostgres))
+	require.Equal(t, `"public"."a.b"`, quoteQualified(`public."a.b"`, quotePostgres))
+	require.Equal(t, "`db`.`users`", quoteQualified("db.users", quoteMySQL))
+}
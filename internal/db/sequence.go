@@ -0,0 +1,30 @@
+package db
+
+import (
+	"fmt"
+)
+
+// SequenceReset describes a sequence (or MySQL AUTO_INCREMENT counter) moved past the loaded rows
+type SequenceReset struct {
+	Table  string `json:"table,omitempty"`
+	Column string `json:"column,omitempty"`
+	// Sequence is the sequence name, empty for AUTO_INCREMENT
+	Sequence string `json:"sequence,omitempty"`
+	// Next is the value the next insert gets
+	Next int64 `json:"next"`
+}
+
+// String returns a short description, e.g. "public.users.id (public.users_id_seq): next value 4"
+func (r SequenceReset) String() string {
+	counter := r.Sequence
+	if counter == "" {
+		counter = "AUTO_INCREMENT"
+	}
+
+	if r.Table == "" {
+		// A sequence not owned by any column
+		return fmt.Sprintf("%s: next value %d", counter, r.Next)
+	}
+
+	return fmt.Sprintf("%s.%s (%s): next value %d", r.Table, r.Column, counter, r.Next)
+}
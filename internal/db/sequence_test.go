@@ -0,0 +1,15 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceReset_String(t *testing.T) {
+	require.Equal(t, "public.users.id (public.users_id_seq): next value 4",
+		SequenceReset{Table: "public.users", Column: "id", Sequence: "public.users_id_seq", Next: 4}.String())
+	require.Equal(t, "app.users.id (AUTO_INCREMENT): next value 1",
+		SequenceReset{Table: "app.users", Column: "id", Next: 1}.String())
+	require.Equal(t, "shared_seq: next value 10", SequenceReset{Sequence: "shared_seq", Next: 10}.String())
+}
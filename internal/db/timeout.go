@@ -0,0 +1,27 @@
+package db
+
+import (
+	"time"
+)
+
+// Timeouts limits how long the statements of a load run and wait for locks, so a TRUNCATE
+// blocked by another session fails instead of hanging. Zero leaves the server setting.
+type Timeouts struct {
+	Statement time.Duration
+	Lock      time.Duration
+}
+
+// IsZero reports whether no limit is set
+func (t Timeouts) IsZero() bool {
+	return t.Statement == 0 && t.Lock == 0
+}
+
+// millis rounds d up to whole milliseconds, the unit of statement_timeout and max_execution_time
+func millis(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// seconds rounds d up to whole seconds, the unit of the MySQL lock wait timeouts
+func seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
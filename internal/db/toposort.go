@@ -1,9 +1,23 @@
 package db
 
 import (
-	"fmt"
+	"slices"
+	"strings"
 )
 
+// CycleError is returned by TopoSort for tables that depend on each other.
+// Path starts and ends with the same table, every table depends on the next one.
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "cyclic dependency detected: " + strings.Join(e.Path, " -> ")
+}
+
+// TopoSort orders inputTables and the tables they depend on so that every table comes
+// before the tables it depends on. Independent tables keep the order of inputTables and
+// dependencies are visited by name, so the result doesn't depend on map iteration order.
 func TopoSort(graph map[string][]string, inputTables []string) ([]string, error) {
 	visited := make(map[string]bool)
 	tempMark := make(map[string]bool)
@@ -14,21 +28,31 @@ func TopoSort(graph map[string][]string, inputTables []string) ([]string, error)
 		include[t] = true
 	}
 
+	var stack []string
 	var visit func(string, bool) error
 	visit = func(node string, isDependent bool) error {
 		if visited[node] {
 			return nil
 		}
 		if tempMark[node] {
-			return fmt.Errorf("cyclic dependency detected: %s", node)
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == node {
+					path := append(append([]string{}, stack[i:]...), node)
+					return &CycleError{Path: path}
+				}
+			}
 		}
 
 		tempMark[node] = true
-		for _, dep := range graph[node] {
+		stack = append(stack, node)
+		deps := slices.Clone(graph[node])
+		slices.Sort(deps)
+		for _, dep := range deps {
 			if err := visit(dep, true); err != nil {
 				return err
 			}
 		}
+		stack = stack[:len(stack)-1]
 
 		tempMark[node] = false
 		visited[node] = true
@@ -52,3 +76,47 @@ func TopoSort(graph map[string][]string, inputTables []string) ([]string, error)
 
 	return order, nil
 }
+
+// Levels groups the tables of a TopoSort result by dependency depth, in load order: the first
+// level holds the tables that depend on none of the others, every other table is in the level
+// after its deepest dependency. Tables of one level don't depend on each other.
+func Levels(graph map[string][]string, sorted []string) [][]string {
+	depth := make(map[string]int, len(sorted))
+	var levels [][]string
+	// sorted lists dependents first, so dependencies are seen first from the end
+	for i := len(sorted) - 1; i >= 0; i-- {
+		table := sorted[i]
+		d := 0
+		for _, dep := range graph[table] {
+			if dd, ok := depth[dep]; ok && dd+1 > d {
+				d = dd + 1
+			}
+		}
+		depth[table] = d
+
+		if d == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], table)
+	}
+
+	return levels
+}
+
+// SplitSelfReferences removes self-edges (tables with a foreign key to themselves) from the graph.
+// Such keys order rows within a table, not tables, so the tables are returned separately.
+func SplitSelfReferences(graph map[string][]string) (map[string][]string, map[string]bool) {
+	result := make(map[string][]string, len(graph))
+	selfRefs := map[string]bool{}
+	for table, parents := range graph {
+		for _, parent := range parents {
+			if parent == table {
+				selfRefs[table] = true
+				continue
+			}
+			result[table] = append(result[table], parent)
+		}
+	}
+
+	return result, selfRefs
+}
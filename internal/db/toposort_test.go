@@ -105,3 +105,64 @@ func TestTopoSort(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitSelfReferences(t *testing.T) {
+	graph, selfRefs := SplitSelfReferences(map[string][]string{
+		"categories": {"categories"},
+		"comments":   {"posts", "comments"},
+		"posts":      {"users"},
+	})
+	require.Equal(t, map[string][]string{
+		"comments": {"posts"},
+		"posts":    {"users"},
+	}, graph)
+	require.Equal(t, map[string]bool{"categories": true, "comments": true}, selfRefs)
+
+	sorted, err := TopoSort(graph, []string{"categories", "comments", "posts", "users"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"categories", "comments", "posts", "users"}, sorted)
+}
+
+func TestTopoSort_CycleError(t *testing.T) {
+	graph := map[string][]string{
+		"users":    {"orders"},
+		"orders":   {"users", "products"},
+		"products": {},
+	}
+
+	_, err := TopoSort(graph, []string{"products", "users"})
+	require.EqualError(t, err, "cyclic dependency detected: users -> orders -> users")
+
+	var cycle *CycleError
+	require.ErrorAs(t, err, &cycle)
+	require.Equal(t, []string{"users", "orders", "users"}, cycle.Path)
+}
+
+func TestTopoSort_Deterministic(t *testing.T) {
+	// The same graph with dependencies listed in different orders
+	for _, graph := range []map[string][]string{
+		{"orders": {"users", "products", "coupons"}},
+		{"orders": {"coupons", "products", "users"}},
+	} {
+		sorted, err := TopoSort(graph, []string{"orders", "settings"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"settings", "orders", "users", "products", "coupons"}, sorted)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	graph := map[string][]string{
+		"orders":          {"users"},
+		"orders2products": {"orders", "products"},
+		"reviews":         {"users", "products"},
+	}
+	sorted, err := TopoSort(graph, []string{"orders2products", "reviews", "settings"})
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"users", "products", "settings"},
+		{"orders", "reviews"},
+		{"orders2products"},
+	}, Levels(graph, sorted))
+	require.Empty(t, Levels(graph, nil))
+}
@@ -0,0 +1,110 @@
+package directive
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+var (
+	directiveRe = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)(?:\((.*)\))?$`)
+	nameRe      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Args describes a single directive call, e.g. `password: $bcrypt(secret)`
+type Args struct {
+	// Name is the directive name without the leading "$" (bcrypt)
+	Name string
+	// Arg is the raw text between the parentheses (secret), empty if there are none
+	Arg string
+	// Table and Column the value is computed for
+	Table  string
+	Column string
+	// Index is the zero-based position of the row within its table
+	Index int
+	// Row is the row being built. Columns are resolved in alphabetical order, so it holds
+	// final values for the columns before Column and raw fixture values for the rest.
+	// It must not be modified.
+	Row map[string]any
+	// Tx is the transaction the row is inserted in
+	Tx db.Querier
+	// DryRun is set when the load only prints the planned statements
+	DryRun bool
+}
+
+// Func computes the value of a directive
+type Func func(ctx context.Context, args Args) (any, error)
+
+// Registry is a concurrency-safe set of named directives
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string]Func
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{funcs: map[string]Func{}}
+}
+
+// Default is the process-wide registry used by every load
+var Default = NewRegistry()
+
+// Register adds fn under name, replacing a previous directive with the same name.
+// It panics if the name is not a valid identifier or fn is nil.
+func (r *Registry) Register(name string, fn Func) {
+	if !nameRe.MatchString(name) {
+		panic(fmt.Sprintf("directive: invalid name %q", name))
+	}
+	if fn == nil {
+		panic(fmt.Sprintf("directive: nil func for %q", name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.funcs[name] = fn
+}
+
+// Lookup returns the directive registered under name
+func (r *Registry) Lookup(name string) (Func, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, ok := r.funcs[name]
+
+	return fn, ok
+}
+
+// Names returns the registered directive names in alphabetical order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Parse splits a directive call of the form `$name` or `$name(arg)`.
+// It doesn't check whether the directive exists.
+func Parse(val any) (name, arg string, ok bool) {
+	s, isStr := val.(string)
+	if !isStr {
+		return "", "", false
+	}
+
+	m := directiveRe.FindStringSubmatch(s)
+	if len(m) != 3 {
+		return "", "", false
+	}
+
+	return m[1], m[2], true
+}
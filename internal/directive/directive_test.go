@@ -0,0 +1,64 @@
+package directive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input any
+		fn    string
+		arg   string
+		ok    bool
+	}{
+		{name: "without args", input: "$uuid", fn: "uuid", ok: true},
+		{name: "with args", input: "$bcrypt(secret)", fn: "bcrypt", arg: "secret", ok: true},
+		{name: "empty parens", input: "$tenant()", fn: "tenant", ok: true},
+		{name: "nested parens", input: "$eval(SELECT max(id) FROM t)", fn: "eval", arg: "SELECT max(id) FROM t", ok: true},
+		{name: "not a string", input: 42, ok: false},
+		{name: "plain string", input: "hello", ok: false},
+		{name: "dollar amount", input: "$100", ok: false},
+		{name: "unclosed", input: "$eval(SELECT 1", ok: false},
+		{name: "text around", input: "price: $now", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, arg, ok := Parse(tt.input)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.fn, fn)
+			require.Equal(t, tt.arg, arg)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	_, ok := r.Lookup("tenant")
+	require.False(t, ok)
+
+	r.Register("tenant", func(ctx context.Context, args Args) (any, error) { return "acme", nil })
+	r.Register("bcrypt", func(ctx context.Context, args Args) (any, error) { return "hash:" + args.Arg, nil })
+
+	fn, ok := r.Lookup("bcrypt")
+	require.True(t, ok)
+	val, err := fn(context.Background(), Args{Arg: "secret"})
+	require.NoError(t, err)
+	require.Equal(t, "hash:secret", val)
+
+	require.Equal(t, []string{"bcrypt", "tenant"}, r.Names())
+}
+
+func TestRegistry_RegisterInvalid(t *testing.T) {
+	r := NewRegistry()
+	fn := func(ctx context.Context, args Args) (any, error) { return nil, nil }
+
+	require.Panics(t, func() { r.Register("", fn) })
+	require.Panics(t, func() { r.Register("bad name", fn) })
+	require.Panics(t, func() { r.Register("ok", nil) })
+}
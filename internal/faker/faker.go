@@ -0,0 +1,163 @@
+package faker
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// Faker generates realistic fake values.
+//
+// Every value is derived from the seed and a key identifying the slot being filled
+// (e.g. table, row and column), so the same seed always produces the same data,
+// regardless of the order in which values are generated.
+type Faker struct {
+	seed uint64
+}
+
+// New creates a Faker with the given seed. A zero seed picks a random one.
+func New(seed int64) *Faker {
+	s := uint64(seed)
+	if s == 0 {
+		s = rand.Uint64()
+	}
+
+	return &Faker{seed: s}
+}
+
+// Seed returns the seed in use, passing it to New reproduces the same values
+func (f *Faker) Seed() int64 {
+	return int64(f.seed)
+}
+
+type generator func(r *rand.Rand) string
+
+var generators = map[string]generator{
+	"first_name": func(r *rand.Rand) string { return pick(r, firstNames) },
+	"last_name":  func(r *rand.Rand) string { return pick(r, lastNames) },
+	"name":       func(r *rand.Rand) string { return pick(r, firstNames) + " " + pick(r, lastNames) },
+	"username":   username,
+	"email": func(r *rand.Rand) string {
+		return username(r) + "@" + pick(r, domains)
+	},
+	"phone": func(r *rand.Rand) string {
+		return fmt.Sprintf("+1-%03d-%03d-%04d", 200+r.IntN(800), r.IntN(1000), r.IntN(10000))
+	},
+	"uuid":     uuid,
+	"street":   street,
+	"city":     func(r *rand.Rand) string { return pick(r, cities) },
+	"country":  func(r *rand.Rand) string { return pick(r, countries) },
+	"postcode": func(r *rand.Rand) string { return fmt.Sprintf("%05d", r.IntN(100000)) },
+	"address": func(r *rand.Rand) string {
+		return fmt.Sprintf("%s, %s %05d, %s", street(r), pick(r, cities), r.IntN(100000), pick(r, countries))
+	},
+	"company":   func(r *rand.Rand) string { return pick(r, lastNames) + " " + pick(r, companySuffixes) },
+	"url":       func(r *rand.Rand) string { return "https://" + pick(r, loremWords) + "." + pick(r, domains) },
+	"word":      func(r *rand.Rand) string { return pick(r, loremWords) },
+	"sentence":  sentence,
+	"paragraph": paragraph,
+}
+
+// Kinds returns the names of all supported generators
+func Kinds() []string {
+	kinds := make([]string, 0, len(generators))
+	for k := range generators {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	return kinds
+}
+
+// Generate produces a value of the given kind for the slot identified by key
+func (f *Faker) Generate(kind, key string) (string, error) {
+	gen, ok := generators[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown fake kind %q (supported: %s)", kind, strings.Join(Kinds(), ", "))
+	}
+
+	return gen(f.rand(kind + "\x00" + key)), nil
+}
+
+func (f *Faker) rand(key string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return rand.New(rand.NewPCG(f.seed, h.Sum64()))
+}
+
+func pick(r *rand.Rand, list []string) string {
+	return list[r.IntN(len(list))]
+}
+
+func username(r *rand.Rand) string {
+	return strings.ToLower(pick(r, firstNames)) + "." + strings.ToLower(pick(r, lastNames)) + fmt.Sprint(r.IntN(1000))
+}
+
+func uuid(r *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(r.UintN(256))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func street(r *rand.Rand) string {
+	return fmt.Sprintf("%d %s %s", 1+r.IntN(9999), pick(r, lastNames), pick(r, streetSuffixes))
+}
+
+func sentence(r *rand.Rand) string {
+	words := make([]string, 4+r.IntN(8))
+	for i := range words {
+		words[i] = pick(r, loremWords)
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+
+	return strings.Join(words, " ") + "."
+}
+
+func paragraph(r *rand.Rand) string {
+	sentences := make([]string, 3+r.IntN(4))
+	for i := range sentences {
+		sentences[i] = sentence(r)
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+var (
+	firstNames = []string{
+		"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "William", "Elizabeth",
+		"David", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen",
+		"Daniel", "Nancy", "Matthew", "Lisa", "Anthony", "Betty", "Mark", "Margaret", "Paul", "Sandra",
+		"Steven", "Ashley", "Andrew", "Emily", "Joshua", "Donna", "Kevin", "Michelle", "Brian", "Olivia",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+		"Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin",
+		"Lee", "Perez", "Thompson", "White", "Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson",
+		"Walker", "Young", "Allen", "King", "Wright", "Scott", "Torres", "Nguyen", "Hill", "Flores",
+	}
+	domains         = []string{"example.com", "example.org", "example.net", "mail.test", "test.dev"}
+	streetSuffixes  = []string{"Street", "Avenue", "Road", "Lane", "Drive", "Court", "Boulevard", "Way"}
+	companySuffixes = []string{"Inc", "LLC", "Group", "Ltd", "Corp", "& Sons", "Partners"}
+	cities          = []string{
+		"New York", "London", "Paris", "Berlin", "Madrid", "Rome", "Tokyo", "Toronto", "Sydney", "Amsterdam",
+		"Vienna", "Prague", "Lisbon", "Dublin", "Oslo", "Helsinki", "Warsaw", "Chicago", "Boston", "Seattle",
+	}
+	countries = []string{
+		"United States", "United Kingdom", "France", "Germany", "Spain", "Italy", "Japan", "Canada", "Australia",
+		"Netherlands", "Austria", "Czech Republic", "Portugal", "Ireland", "Norway", "Finland", "Poland",
+	}
+	loremWords = []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod",
+		"tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim", "ad", "minim",
+		"veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi", "aliquip", "ex", "ea",
+		"commodo", "consequat", "duis", "aute", "irure", "in", "reprehenderit", "voluptate", "velit", "esse",
+	}
+)
@@ -0,0 +1,77 @@
+package faker
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaker_Generate_Deterministic(t *testing.T) {
+	f1 := New(42)
+	f2 := New(42)
+
+	for _, kind := range Kinds() {
+		v1, err := f1.Generate(kind, "public.users/0/col")
+		require.NoError(t, err)
+		v2, err := f2.Generate(kind, "public.users/0/col")
+		require.NoError(t, err)
+		require.Equal(t, v1, v2, kind)
+		require.NotEmpty(t, v1, kind)
+	}
+}
+
+func TestFaker_Seed_ReproducesRandomSeed(t *testing.T) {
+	random := New(0)
+	require.NotZero(t, random.Seed())
+
+	again := New(random.Seed())
+	for _, key := range []string{"a", "b"} {
+		v1, _ := random.Generate("uuid", key)
+		v2, _ := again.Generate("uuid", key)
+		require.Equal(t, v1, v2)
+	}
+}
+
+func TestFaker_Generate_OrderIndependent(t *testing.T) {
+	f1 := New(7)
+	a1, _ := f1.Generate("email", "a")
+	b1, _ := f1.Generate("email", "b")
+
+	f2 := New(7)
+	b2, _ := f2.Generate("email", "b")
+	a2, _ := f2.Generate("email", "a")
+
+	require.Equal(t, a1, a2)
+	require.Equal(t, b1, b2)
+	require.NotEqual(t, a1, b1)
+}
+
+func TestFaker_Generate_DifferentSeeds(t *testing.T) {
+	v1, _ := New(1).Generate("uuid", "key")
+	v2, _ := New(2).Generate("uuid", "key")
+	require.NotEqual(t, v1, v2)
+}
+
+func TestFaker_Generate_Formats(t *testing.T) {
+	f := New(1)
+
+	uuid, err := f.Generate("uuid", "k")
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), uuid)
+
+	email, err := f.Generate("email", "k")
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@[a-z.]+$`), email)
+
+	name, err := f.Generate("name", "k")
+	require.NoError(t, err)
+	require.Len(t, strings.Fields(name), 2)
+}
+
+func TestFaker_Generate_UnknownKind(t *testing.T) {
+	_, err := New(1).Generate("spaceship", "k")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown fake kind")
+}
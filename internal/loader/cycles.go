@@ -0,0 +1,208 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/parser"
+)
+
+// cyclePlan records how foreign key cycles between the fixture tables are broken
+type cyclePlan struct {
+	// deferConstraints is set when DEFERRABLE constraints are checked at commit only
+	deferConstraints bool
+	// nullFirst lists per table the nullable foreign key columns inserted as NULL
+	// and filled in by an UPDATE once all rows exist
+	nullFirst map[string][]string
+}
+
+// pendingUpdate sets postponed foreign key values on an inserted row
+type pendingUpdate struct {
+	table    string
+	location string
+	key      map[string]any
+	values   map[string]any
+}
+
+// sortTables orders the tables like db.TopoSort. Cycles are broken one edge at a time,
+// preferring edges that need no work at all, then DEFERRABLE constraints, then nullable
+// foreign keys. The graph without the broken edges is returned along with the order.
+func (l *Loader) sortTables(ctx context.Context, deps map[string][]string, tables []string, fixtures parser.Fixtures) ([]string, map[string][]string, cyclePlan, error) {
+	plan := cyclePlan{nullFirst: map[string][]string{}}
+	for {
+		sorted, err := db.TopoSort(deps, tables)
+		var cycle *db.CycleError
+		if !errors.As(err, &cycle) {
+			return sorted, deps, plan, err
+		}
+
+		deps, err = l.breakCycle(ctx, deps, cycle, fixtures, &plan)
+		if err != nil {
+			return nil, nil, plan, err
+		}
+	}
+}
+
+// breakCycle removes one edge of the cycle from deps and records in plan what it takes
+// to insert the rows without it
+func (l *Loader) breakCycle(ctx context.Context, deps map[string][]string, cycle *db.CycleError, fixtures parser.Fixtures, plan *cyclePlan) (map[string][]string, error) {
+	path := cycle.Path
+
+	// A table without rows only takes part in the cleanup, its foreign keys don't matter
+	for i := 0; i+1 < len(path); i++ {
+		if len(fixtures[path[i]]) == 0 {
+			log.Printf("[cycle] %s -> %s: no rows in %s", path[i], path[i+1], path[i])
+
+			return removeEdge(deps, path[i], path[i+1]), nil
+		}
+	}
+
+	tables := path[:len(path)-1]
+	keys, err := l.Database.GetForeignKeys(ctx, l.DB, tables)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := l.Database.GetColumns(ctx, l.DB, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	best, bestNulls := -1, []string(nil)
+	bestDeferred := false
+	for i := 0; i+1 < len(path); i++ {
+		child, parent := path[i], path[i+1]
+
+		found, deferred, ok := false, false, true
+		var nulls []string
+		for _, fk := range keys {
+			if fk.Table != child || fk.RefTable != parent {
+				continue
+			}
+			found = true
+
+			switch {
+			case fk.Deferrable:
+				deferred = true
+			case allNullable(columns, child, fk.Columns):
+				nulls = append(nulls, fk.Columns...)
+			default:
+				ok = false
+			}
+		}
+		if !found || !ok {
+			continue
+		}
+
+		// Deferring constraints needs no extra statements, prefer it
+		if best < 0 || (len(nulls) == 0 && len(bestNulls) > 0) {
+			best, bestNulls, bestDeferred = i, nulls, deferred
+		}
+	}
+
+	if best < 0 {
+		return nil, fmt.Errorf("%w: no DEFERRABLE or nullable foreign key to break it", cycle)
+	}
+
+	child, parent := path[best], path[best+1]
+	if bestDeferred {
+		plan.deferConstraints = true
+		log.Printf("[cycle] %s -> %s: deferring constraints until commit", child, parent)
+	}
+	if len(bestNulls) > 0 {
+		plan.nullFirst[child] = append(plan.nullFirst[child], bestNulls...)
+		log.Printf("[cycle] %s -> %s: inserting %s as NULL, set by UPDATE after all rows",
+			child, parent, strings.Join(bestNulls, ", "))
+	}
+
+	return removeEdge(deps, child, parent), nil
+}
+
+// allNullable reports whether all columns of the table accept NULL
+func allNullable(catalog db.Catalog, table string, columns []string) bool {
+	for _, col := range columns {
+		if c, ok := catalog.Column(table, col); !ok || !c.Nullable {
+			return false
+		}
+	}
+
+	return true
+}
+
+// removeEdge returns a copy of the graph without the child -> parent dependency
+func removeEdge(deps map[string][]string, child, parent string) map[string][]string {
+	graph := make(map[string][]string, len(deps))
+	for table, parents := range deps {
+		graph[table] = parents
+	}
+
+	var kept []string
+	for _, p := range deps[child] {
+		if p != parent {
+			kept = append(kept, p)
+		}
+	}
+	graph[child] = kept
+
+	return graph
+}
+
+// postpone moves the non-null values of the table's nullFirst columns from row into
+// a pending update, keyed by the primary key values of the row
+func (l *Loader) postpone(table string, index int, row, processedRow map[string]any) error {
+	values := map[string]any{}
+	for _, col := range l.nullFirst[table] {
+		if val, ok := processedRow[col]; ok && val != nil {
+			values[col] = val
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	key := map[string]any{}
+	for _, col := range sortedKeys(l.columns[table]) {
+		if !l.columns[table][col].PrimaryKey {
+			continue
+		}
+
+		val, ok := processedRow[col]
+		if !ok || val == nil {
+			return fmt.Errorf("%s: primary key column %q is needed to set %s after insert",
+				rowLocation(index, row), col, strings.Join(sortedKeys(values), ", "))
+		}
+		key[col] = val
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("%s: a primary key is needed to set %s after insert",
+			rowLocation(index, row), strings.Join(sortedKeys(values), ", "))
+	}
+
+	for col := range values {
+		processedRow[col] = nil
+	}
+	l.mu.Lock()
+	l.updates = append(l.updates, pendingUpdate{
+		table:    table,
+		location: rowLocation(index, row),
+		key:      key,
+		values:   values,
+	})
+	l.mu.Unlock()
+
+	return nil
+}
+
+// applyUpdates fills in the foreign keys postponed by postpone
+func (l *Loader) applyUpdates(ctx context.Context, q db.Querier) error {
+	for _, u := range l.updates {
+		if err := l.Database.UpdateRow(ctx, q, u.table, u.key, u.values, l.Config.DryRun); err != nil {
+			return fmt.Errorf("update %q %s: %w", u.table, u.location, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+const cycleFixtures = `
+public.users:
+  - id: 1
+    last_order_id: 10
+  - id: 2
+public.orders:
+  - id: 10
+    user_id: 1
+`
+
+// users.last_order_id -> orders and orders.user_id -> users
+func cycleMock(deferrable bool, nullable bool) *MockDatabase {
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).
+		Return(map[string]string{"public.users": "public.users", "public.orders": "public.orders"}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
+		"public.users":  {"public.orders"},
+		"public.orders": {"public.users"},
+	}, nil)
+	mockDB.On("GetForeignKeys", mock.Anything, mock.Anything, mock.Anything).Return([]db.ForeignKey{
+		{Name: "orders_user_fk", Table: "public.orders", Columns: []string{"user_id"},
+			RefTable: "public.users", RefColumns: []string{"id"}, Deferrable: deferrable},
+		{Name: "users_last_order_fk", Table: "public.users", Columns: []string{"last_order_id"},
+			RefTable: "public.orders", RefColumns: []string{"id"}, Deferrable: deferrable},
+	}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, mock.Anything).Return(db.Catalog{
+		"public.users": {
+			"id":            {Name: "id", DataType: "integer", HasDefault: true, PrimaryKey: true},
+			"last_order_id": {Name: "last_order_id", DataType: "integer", Nullable: nullable},
+		},
+		"public.orders": {
+			"id":      {Name: "id", DataType: "integer", HasDefault: true, PrimaryKey: true},
+			"user_id": {Name: "user_id", DataType: "integer"},
+		},
+	}, nil)
+
+	return mockDB
+}
+
+func writeCycleFixtures(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixtures.yml")
+	require.NoError(t, os.WriteFile(path, []byte(cycleFixtures), 0644))
+
+	return path
+}
+
+func TestLoader_Load_CycleNullableForeignKey(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB := cycleMock(false, true)
+	var inserted []string
+	var users []map[string]any
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, false).
+		Run(func(args mock.Arguments) {
+			inserted = append(inserted, args.String(2))
+			if args.String(2) == "public.users" {
+				users = append(users, args.Get(3).(map[string]any))
+			}
+		}).
+		Return(nil)
+	mockDB.On("UpdateRow", mock.Anything, mock.Anything, "public.users",
+		map[string]any{"id": 1}, map[string]any{"last_order_id": 10}, false).Return(nil).Once()
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: writeCycleFixtures(t)},
+	}
+	require.NoError(t, loader.Load(context.Background()))
+
+	// users go first, with the reference to orders left NULL until the UPDATE
+	require.Equal(t, []string{"public.users", "public.users", "public.orders"}, inserted)
+	require.Equal(t, []map[string]any{{"id": 1, "last_order_id": nil}, {"id": 2}}, users)
+	mockDB.AssertNotCalled(t, "DeferConstraints", mock.Anything, mock.Anything, mock.Anything)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_Load_CycleDeferrable(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB := cycleMock(true, false)
+	mockDB.On("DeferConstraints", mock.Anything, mock.Anything, false).Return(nil).Once()
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: writeCycleFixtures(t)},
+	}
+	require.NoError(t, loader.Load(context.Background()))
+
+	mockDB.AssertNotCalled(t, "UpdateRow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_Load_CycleUnbreakable(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: cycleMock(false, false),
+		Config:   LoaderConfig{FilePath: writeCycleFixtures(t)},
+	}
+	err = loader.Load(context.Background())
+	require.Error(t, err)
+	require.Regexp(t, `^cyclic dependency detected: public\.(users|orders) -> public\.(users|orders) -> public\.(users|orders): `+
+		`no DEFERRABLE or nullable foreign key to break it$`, err.Error())
+
+	var cycle *db.CycleError
+	require.ErrorAs(t, err, &cycle)
+	require.Len(t, cycle.Path, 3)
+
+	// Nothing was written
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRemoveEdge(t *testing.T) {
+	deps := map[string][]string{"a": {"b", "c"}, "b": {"a"}}
+	graph := removeEdge(deps, "a", "b")
+
+	require.Equal(t, map[string][]string{"a": {"c"}, "b": {"a"}}, graph)
+	require.Equal(t, []string{"b", "c"}, deps["a"])
+}
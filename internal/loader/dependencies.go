@@ -0,0 +1,43 @@
+package loader
+
+import (
+	"log"
+	"slices"
+)
+
+// mergeDependencies adds the dependencies declared in the fixture file to the graph read
+// from the foreign keys. Declared table names are canonicalized with resolved first.
+// deps is not modified; every edge the graph didn't have yet is logged.
+func mergeDependencies(deps, declared map[string][]string, resolved map[string]string) map[string][]string {
+	if len(declared) == 0 {
+		return deps
+	}
+
+	canonical := func(table string) string {
+		if name, ok := resolved[table]; ok {
+			return name
+		}
+
+		return table
+	}
+
+	graph := make(map[string][]string, len(deps)+len(declared))
+	for table, parents := range deps {
+		graph[table] = slices.Clone(parents)
+	}
+
+	for _, table := range sortedKeys(declared) {
+		child := canonical(table)
+		for _, p := range declared[table] {
+			parent := canonical(p)
+			if slices.Contains(graph[child], parent) {
+				continue
+			}
+
+			graph[child] = append(graph[child], parent)
+			log.Printf("[dependency] %s -> %s (declared in fixtures)", child, parent)
+		}
+	}
+
+	return graph
+}
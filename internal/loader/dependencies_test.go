@@ -0,0 +1,76 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+func TestMergeDependencies(t *testing.T) {
+	deps := map[string][]string{"public.orders": {"public.users"}}
+	declared := map[string][]string{
+		"audit_log":     {"users", "public.settings"},
+		"public.orders": {"public.users"},
+	}
+	resolved := map[string]string{"audit_log": "public.audit_log", "users": "public.users"}
+
+	graph := mergeDependencies(deps, declared, resolved)
+	require.Equal(t, map[string][]string{
+		"public.orders":    {"public.users"},
+		"public.audit_log": {"public.users", "public.settings"},
+	}, graph)
+	require.Equal(t, map[string][]string{"public.orders": {"public.users"}}, deps)
+
+	require.Equal(t, deps, mergeDependencies(deps, nil, resolved))
+}
+
+func TestLoader_Load_DeclaredDependencies(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+dependencies:
+  audit_log: [users]
+audit_log:
+  - id: 1
+users:
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).
+		Return(map[string]string{"audit_log": "public.audit_log", "users": "public.users"}, nil)
+	// No foreign key between the tables, only the declared dependency orders them
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"public.audit_log", "public.users"}).Return(db.Catalog{}, nil)
+
+	var inserted []string
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, false).
+		Run(func(args mock.Arguments) { inserted = append(inserted, args.String(2)) }).
+		Return(nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath},
+	}
+	require.NoError(t, loader.Load(context.Background()))
+
+	require.Equal(t, []string{"public.users", "public.audit_log"}, inserted)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,100 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/directive"
+)
+
+// builtinNames are directives implemented by the loader itself. They take precedence
+// over the registry, so custom directives can't change their meaning.
+var builtinNames = map[string]bool{
+	"eval":  true,
+	"now":   true,
+	"today": true,
+	"time":  true,
+	"fake":  true,
+	"uuid":  true,
+}
+
+// IsBuiltin reports whether name is a built-in directive
+func IsBuiltin(name string) bool {
+	return builtinNames[name]
+}
+
+func (l *Loader) lookupFunc(name string) (directive.Func, bool) {
+	switch name {
+	case "eval":
+		return l.evalFunc, true
+	case "now", "today", "time":
+		return l.timeFunc, true
+	case "fake", "uuid":
+		return l.fakeFunc, true
+	}
+
+	funcs := l.Funcs
+	if funcs == nil {
+		funcs = directive.Default
+	}
+
+	return funcs.Lookup(name)
+}
+
+// evalFunc implements $eval(<sql>): the query must return exactly one value
+func (l *Loader) evalFunc(ctx context.Context, args directive.Args) (any, error) {
+	expr := strings.TrimSpace(args.Arg)
+	if expr == "" {
+		return nil, errors.New("empty expression")
+	}
+
+	if rw, ok := l.Database.(db.ExprRewriter); ok {
+		expr = rw.RewriteExpr(expr)
+	}
+
+	var val any
+	if err := args.Tx.QueryRowContext(ctx, expr).Scan(&val); err != nil {
+		return nil, fmt.Errorf("eval %q: %w", expr, err)
+	}
+
+	return val, nil
+}
+
+// timeFunc implements $now, $today and $time against the load's reference time
+func (l *Loader) timeFunc(_ context.Context, args directive.Args) (any, error) {
+	arg := strings.TrimSpace(args.Arg)
+	if args.Name == "time" && arg == "" {
+		return nil, errors.New("timestamp is required")
+	}
+
+	now := l.startedAt
+	if now.IsZero() {
+		now = l.now()
+	}
+
+	return evalTime(args.Name, arg, now)
+}
+
+// fakeFunc implements $fake(<kind>) and $uuid
+func (l *Loader) fakeFunc(_ context.Context, args directive.Args) (any, error) {
+	kind := "uuid"
+	if args.Name == "fake" {
+		kind = strings.TrimSpace(args.Arg)
+		if kind == "" {
+			return nil, errors.New("kind is required")
+		}
+	} else if args.Arg != "" {
+		return nil, errors.New("$uuid takes no arguments")
+	}
+
+	if l.faker == nil {
+		l.faker = l.newFaker()
+	}
+
+	// The key pins the value to its slot, so reruns with the same seed
+	// produce identical data regardless of evaluation order
+	return l.faker.Generate(kind, fmt.Sprintf("%s/%d/%s", args.Table, args.Index, args.Column))
+}
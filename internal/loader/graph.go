@@ -0,0 +1,185 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Graph is the dependency graph of the fixture tables and the tables they depend on
+type Graph struct {
+	// Nodes are sorted by name
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// GraphNode is a table of the graph
+type GraphNode struct {
+	Name string
+	Rows int
+	// Fixture is set for tables of the fixture file, the others are parents pulled in
+	// through the dependencies
+	Fixture bool
+}
+
+// GraphEdge is a dependency: Table references DependsOn
+type GraphEdge struct {
+	Table     string
+	DependsOn string
+	// Declared is set for dependencies from the fixture file rather than a foreign key
+	Declared bool
+	// Cycle is set when DependsOn depends back on Table, directly or not
+	Cycle bool
+}
+
+// Graph reads the dependencies of the fixture tables. Unlike Plan it doesn't order the
+// tables, so cycles that can't be loaded are shown too.
+func (l *Loader) Graph(ctx context.Context) (*Graph, error) {
+	fg, err := l.readGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fixture tables and everything they depend on, like TopoSort
+	seen := map[string]bool{}
+	var visit func(string)
+	visit = func(table string) {
+		if seen[table] {
+			return
+		}
+		seen[table] = true
+		for _, parent := range fg.graph[table] {
+			visit(parent)
+		}
+	}
+	for _, table := range sortedKeys(fg.fixtures) {
+		visit(table)
+	}
+
+	g := &Graph{}
+	for _, table := range sortedKeys(seen) {
+		rows, ok := fg.fixtures[table]
+		g.Nodes = append(g.Nodes, GraphNode{Name: table, Rows: len(rows), Fixture: ok})
+
+		parents := slices.Clone(fg.graph[table])
+		slices.Sort(parents)
+		for _, parent := range parents {
+			g.Edges = append(g.Edges, GraphEdge{
+				Table:     table,
+				DependsOn: parent,
+				Declared:  !slices.Contains(fg.foreignKeys[table], parent),
+				Cycle:     reaches(fg.graph, parent, table),
+			})
+		}
+	}
+
+	return g, nil
+}
+
+// reaches reports whether to can be reached from from
+func reaches(graph map[string][]string, from, to string) bool {
+	seen := map[string]bool{}
+	var visit func(string) bool
+	visit = func(table string) bool {
+		if table == to {
+			return true
+		}
+		if seen[table] {
+			return false
+		}
+		seen[table] = true
+
+		return slices.ContainsFunc(graph[table], visit)
+	}
+
+	return visit(from)
+}
+
+// WriteDOT renders the graph for Graphviz. Fixture tables are filled, declared
+// dependencies dashed and edges on a cycle red.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph fixtures {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=%s", dotQuote(n.Name+"\n"+pluralRows(n.Rows)))
+		if n.Fixture {
+			attrs += `, style=filled, fillcolor="#cde4ff"`
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.Name), attrs)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Declared {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.Cycle {
+			attrs = append(attrs, "color=red")
+		}
+
+		fmt.Fprintf(&b, "  %s -> %s", dotQuote(e.Table), dotQuote(e.DependsOn))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid renders the graph as a Mermaid flowchart, with the same conventions as WriteDOT
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.Name] = fmt.Sprintf("n%d", i)
+
+		fmt.Fprintf(&b, "  %s[\"%s<br/>%s\"]", ids[n.Name], mermaidEscape(n.Name), pluralRows(n.Rows))
+		if n.Fixture {
+			b.WriteString(":::fixture")
+		}
+		b.WriteString("\n")
+	}
+
+	var cycles []string
+	for i, e := range g.Edges {
+		arrow := "-->"
+		if e.Declared {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.Table], arrow, ids[e.DependsOn])
+
+		if e.Cycle {
+			cycles = append(cycles, fmt.Sprint(i))
+		}
+	}
+
+	b.WriteString("  classDef fixture fill:#cde4ff\n")
+	if len(cycles) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:red\n", strings.Join(cycles, ","))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote returns s as a DOT string, with newlines kept as line breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// mermaidEscape replaces the characters that end a Mermaid label
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
@@ -0,0 +1,98 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_Graph(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+dependencies:
+  public.audit_log: [public.users]
+public.users:
+  - id: 1
+  - id: 2
+public.audit_log:
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).Return(map[string]string{
+		"public.audit_log": "public.audit_log", "public.users": "public.users",
+	}, nil)
+	// users and accounts reference each other, orders isn't reachable from the fixtures
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
+		"public.users":    {"public.accounts"},
+		"public.accounts": {"public.users"},
+		"public.orders":   {"public.users"},
+	}, nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath},
+	}
+	graph, err := loader.Graph(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, &Graph{
+		Nodes: []GraphNode{
+			{Name: "public.accounts", Rows: 0},
+			{Name: "public.audit_log", Rows: 1, Fixture: true},
+			{Name: "public.users", Rows: 2, Fixture: true},
+		},
+		Edges: []GraphEdge{
+			{Table: "public.accounts", DependsOn: "public.users", Cycle: true},
+			{Table: "public.audit_log", DependsOn: "public.users", Declared: true},
+			{Table: "public.users", DependsOn: "public.accounts", Cycle: true},
+		},
+	}, graph)
+
+	var dot strings.Builder
+	require.NoError(t, graph.WriteDOT(&dot))
+	require.Equal(t, `digraph fixtures {
+  rankdir=LR;
+  node [shape=box];
+  "public.accounts" [label="public.accounts\n0 rows"];
+  "public.audit_log" [label="public.audit_log\n1 row", style=filled, fillcolor="#cde4ff"];
+  "public.users" [label="public.users\n2 rows", style=filled, fillcolor="#cde4ff"];
+  "public.accounts" -> "public.users" [color=red];
+  "public.audit_log" -> "public.users" [style=dashed];
+  "public.users" -> "public.accounts" [color=red];
+}
+`, dot.String())
+
+	var mermaid strings.Builder
+	require.NoError(t, graph.WriteMermaid(&mermaid))
+	require.Equal(t, `flowchart LR
+  n0["public.accounts<br/>0 rows"]
+  n1["public.audit_log<br/>1 row"]:::fixture
+  n2["public.users<br/>2 rows"]:::fixture
+  n0 --> n2
+  n1 -.-> n2
+  n2 --> n0
+  classDef fixture fill:#cde4ff
+  linkStyle 0,2 stroke:red
+`, mermaid.String())
+
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestDotQuote(t *testing.T) {
+	require.Equal(t, `"public.\"odd\\name\"\n3 rows"`, dotQuote("public.\"odd\\name\"\n3 rows"))
+}
@@ -4,132 +4,439 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"regexp"
-	"strings"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/directive"
+	"github.com/rom8726/pgfixtures/internal/faker"
 	"github.com/rom8726/pgfixtures/internal/parser"
 )
 
-// Regular expression to match PostgreSQL interval syntax
-// Example: "INTERVAL '1 day'" -> "INTERVAL 1 DAY"
-var intervalRegex = regexp.MustCompile(`INTERVAL\s+'(\d+)\s+([^']+)'`)
-
-// convertIntervalSyntax converts PostgreSQL interval syntax to MySQL syntax
-func convertIntervalSyntax(expr string) string {
-	return intervalRegex.ReplaceAllStringFunc(expr, func(match string) string {
-		// Extract the number and unit from the interval
-		parts := intervalRegex.FindStringSubmatch(match)
-		if len(parts) != 3 {
-			return match // Return the original if no match
-		}
-
-		number := parts[1]
-		unit := strings.ToUpper(parts[2])
-
-		// Return the MySQL syntax
-		return fmt.Sprintf("INTERVAL %s %s", number, unit)
-	})
-}
-
 type LoaderConfig struct {
 	FilePath string
 	Truncate bool
 	ResetSeq bool
 	DryRun   bool
+	// Now is the clock used by $now, $today and friends (time.Now if nil)
+	Now func() time.Time
+	// Seed makes $fake and $uuid values reproducible (0 picks a random seed)
+	Seed int64
+	// CheckSchema validates all rows against the catalog before anything is written
+	CheckSchema bool
+	// StrictGenerated rejects values for generated columns instead of dropping them
+	StrictGenerated bool
+	// Workers above 1 loads the tables of each dependency level concurrently, see loadParallel
+	Workers int
+	// Tx selects the transactions, isolation level and timeouts of the load
+	Tx TxConfig
+	// Retry waits for the database and retries loads failing with a transient error
+	Retry RetryConfig
+	// Lock serializes loads that use the same lock name
+	Lock LockConfig
 }
 
 type Loader struct {
 	DB       *sql.DB
 	Config   LoaderConfig
 	Database db.Database
+	// Funcs holds custom value directives (directive.Default if nil)
+	Funcs *directive.Registry
+
+	// startedAt is the reference time shared by all time directives of one load
+	startedAt time.Time
+	faker     *faker.Faker
+	// columns describes the fixture tables, used to convert values for the driver
+	columns db.Catalog
+	// nullFirst and updates postpone foreign keys that close a cycle, see sortTables
+	nullFirst map[string][]string
+	updates   []pendingUpdate
+	// mu guards updates, which parallel workers append to
+	mu sync.Mutex
+}
+
+// prepared is what Load has worked out before anything is written
+type prepared struct {
+	*fixtureGraph
+	// sorted lists the tables dependents first, tables are loaded in reverse
+	sorted []string
+	// deps is the graph sorted follows: without self-references and broken cycle edges
+	deps      map[string][]string
+	cycles    cyclePlan
+	sequences []db.SequenceReset
+}
+
+// updatesSequences reports whether the load touches any sequence
+func (p *prepared) updatesSequences(resetSeq bool) bool {
+	return resetSeq || len(p.sequences) > 0
+}
+
+// loadOrder lists the tables in the order they are loaded, parents first
+func (p *prepared) loadOrder() []string {
+	order := slices.Clone(p.sorted)
+	slices.Reverse(order)
+
+	return order
+}
+
+// fixtureGraph is the fixture file with canonical table names and the dependency graph
+type fixtureGraph struct {
+	fixtures parser.Fixtures
+	doc      *parser.Document
+	// graph holds the dependencies from foreign keys and from the fixtures, foreignKeys
+	// the former only
+	graph       map[string][]string
+	foreignKeys map[string][]string
 }
 
 func (l *Loader) Load(ctx context.Context) error {
-	fixtures, err := parser.ParseFile(l.Config.FilePath)
-	if err != nil {
+	if err := l.Config.Tx.validate(l.Config.Workers); err != nil {
 		return err
 	}
-
-	tables := make([]string, 0, len(fixtures))
-	for t := range fixtures {
-		tables = append(tables, t)
+	if err := l.Config.Retry.validate(); err != nil {
+		return err
+	}
+	if err := l.Config.Lock.validate(); err != nil {
+		return err
 	}
 
-	deps, err := l.Database.GetDependencyGraph(ctx, l.DB)
-	if err != nil {
+	if err := l.waitForDB(ctx); err != nil {
 		return err
 	}
 
-	sorted, err := db.TopoSort(deps, tables)
+	return l.withLock(ctx, func() error { return l.load(ctx) })
+}
+
+// load prepares and runs the load, see Load
+func (l *Loader) load(ctx context.Context) error {
+	p, err := l.prepare(ctx)
 	if err != nil {
 		return err
 	}
 
-	tx, err := l.DB.Begin()
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+	parallel := l.Config.Workers > 1 && !l.Config.DryRun
+	mode := l.Config.Tx.Mode
+	if p.cycles.deferConstraints && (parallel || mode == TxPerTable || mode == TxNone) {
+		// Deferred constraints are checked at commit, so all rows need the same transaction
+		log.Println("[tx] deferred constraints need all rows in one transaction, loading in one transaction instead")
+		parallel, mode = false, TxSingle
 	}
 
-	if l.Config.Truncate {
-		if err := l.truncateTables(ctx, tx, sorted); err != nil {
-			_ = tx.Rollback()
+	// A failed single transaction leaves nothing behind, unless some of its statements commit
+	// implicitly. Other loads are only retried with Truncate, which cleans up after them.
+	single := !parallel && mode != TxPerTable && mode != TxNone
+	atomic := single && !l.commitsEarly(p)
+
+	return l.retry(ctx, atomic || l.Config.Truncate, func() error {
+		l.updates = nil
 
+		switch {
+		case parallel:
+			return l.loadParallel(ctx, p)
+		case mode == TxPerTable:
+			return l.loadPerTable(ctx, p)
+		case mode == TxNone:
+			return l.loadWithoutTx(ctx, p)
+		}
+
+		tx, err := l.begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := l.end(ctx, tx, l.loadAll(ctx, tx, p)); err != nil {
 			return err
 		}
+		if err := l.sequencesAfterCommit(ctx, p); err != nil {
+			return l.undo(ctx, p, "all rows", err)
+		}
+
+		return nil
+	})
+}
+
+// commitsEarly reports whether the load runs statements that commit on their own, see
+// db.ImplicitCommits
+func (l *Loader) commitsEarly(p *prepared) bool {
+	commits := l.Database.ImplicitCommits()
+
+	return (commits.Cleanup && l.Config.Truncate) || (commits.Sequences && p.updatesSequences(l.Config.ResetSeq))
+}
+
+// loadAll runs every step of the load on q
+func (l *Loader) loadAll(ctx context.Context, q db.Querier, p *prepared) error {
+	if p.cycles.deferConstraints {
+		if err := l.Database.DeferConstraints(ctx, q, l.Config.DryRun); err != nil {
+			return fmt.Errorf("defer constraints: %w", err)
+		}
 	}
 
-	for i := len(sorted) - 1; i >= 0; i-- {
-		table := sorted[i]
+	if err := l.cleanup(ctx, q, p); err != nil {
+		return err
+	}
 
-		records := fixtures[table]
-		for _, row := range records {
-			if err := l.insertRow(ctx, tx, table, row); err != nil {
-				_ = tx.Rollback()
+	if err := l.loadRows(ctx, q, p.fixtures, p.loadOrder()); err != nil {
+		return err
+	}
 
+	return l.finish(ctx, q, p)
+}
+
+// cleanup empties the tables if Truncate is set
+func (l *Loader) cleanup(ctx context.Context, q db.Querier, p *prepared) error {
+	if !l.Config.Truncate {
+		return nil
+	}
+
+	return l.Database.TruncateTables(ctx, q, p.sorted, l.Config.DryRun)
+}
+
+// loadRows inserts the rows of the tables in the given order
+func (l *Loader) loadRows(ctx context.Context, q db.Querier, fixtures parser.Fixtures, tables []string) error {
+	for _, table := range tables {
+		for idx, row := range fixtures[table] {
+			if err := l.insertRow(ctx, q, table, idx, row); err != nil {
 				return fmt.Errorf("insert into %q: %w", table, err)
 			}
 		}
 	}
 
-	if l.Config.ResetSeq {
-		if err := l.resetSequences(ctx, tx, sorted); err != nil {
-			_ = tx.Rollback()
+	return nil
+}
 
+// finish sets the postponed foreign keys and the sequences, which needs all rows in place.
+// Sequence updates that commit implicitly are left to sequencesAfterCommit.
+func (l *Loader) finish(ctx context.Context, q db.Querier, p *prepared) error {
+	if err := l.applyUpdates(ctx, q); err != nil {
+		return err
+	}
+
+	if l.Database.ImplicitCommits().Sequences {
+		return nil
+	}
+
+	return l.updateSequences(ctx, q, p)
+}
+
+// updateSequences resets the sequences if ResetSeq is set, then applies the sequences section
+func (l *Loader) updateSequences(ctx context.Context, q db.Querier, p *prepared) error {
+	if l.Config.ResetSeq {
+		if err := l.resetSequences(ctx, q, p.sorted); err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit()
+	return l.setSequences(ctx, q, p.sequences)
 }
 
-func (l *Loader) truncateTables(ctx context.Context, tx *sql.Tx, tables []string) error {
-	return l.Database.TruncateTables(ctx, tx, tables, l.Config.DryRun)
+// sequencesAfterCommit runs the sequence updates finish leaves out because they commit
+// implicitly (ALTER TABLE on MySQL). They run after the last commit of the load, outside its
+// transactions, so a failure here leaves the committed rows in place.
+func (l *Loader) sequencesAfterCommit(ctx context.Context, p *prepared) error {
+	if !l.Database.ImplicitCommits().Sequences || !p.updatesSequences(l.Config.ResetSeq) {
+		return nil
+	}
+
+	return l.withConn(ctx, func(conn *sql.Conn) error { return l.updateSequences(ctx, conn, p) })
 }
 
-func (l *Loader) insertRow(ctx context.Context, tx *sql.Tx, table string, row map[string]any) error {
-	// Process $eval expressions
-	processedRow := make(map[string]any)
-	for col, val := range row {
-		if expr, ok := parser.IsEval(val); ok {
-			// Check if we need to convert PostgreSQL interval syntax to MySQL syntax
-			_, isMySQL := l.Database.(*db.MySQLDatabase)
-			if isMySQL {
-				// Convert PostgreSQL interval syntax to MySQL syntax
-				// Example: "SELECT NOW() - INTERVAL '1 day'" -> "SELECT NOW() - INTERVAL 1 DAY"
-				expr = convertIntervalSyntax(expr)
-			}
+// prepare reads the fixtures and the catalog, orders the tables and validates everything
+// that can be checked up front
+func (l *Loader) prepare(ctx context.Context) (*prepared, error) {
+	l.startedAt = l.now()
+	l.faker = l.newFaker()
+
+	fg, err := l.readGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := fg.fixtures
+	tables := sortedKeys(fixtures)
+
+	// Self-references order rows within a table, see orderSelfReferencing
+	deps, selfRefs := db.SplitSelfReferences(fg.graph)
+
+	sorted, deps, cycles, err := l.sortTables(ctx, deps, tables, fixtures)
+	if err != nil {
+		return nil, err
+	}
+	l.nullFirst = cycles.nullFirst
+	l.updates = nil
+
+	l.columns, err = l.Database.GetColumns(ctx, l.DB, sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.Config.StrictGenerated {
+		dropGenerated(fixtures, l.columns)
+	}
+
+	if l.Config.CheckSchema {
+		if err := checkSchema(fixtures, sorted, l.columns); err != nil {
+			return nil, err
+		}
+	}
+
+	sequences, err := l.resolveSequences(ctx, fg.doc.Sequences)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.orderSelfReferencing(ctx, fixtures, selfRefs); err != nil {
+		return nil, err
+	}
+
+	return &prepared{
+		fixtureGraph: fg,
+		sorted:       sorted,
+		deps:         deps,
+		cycles:       cycles,
+		sequences:    sequences,
+	}, nil
+}
 
-			if err := tx.QueryRowContext(ctx, expr).Scan(&val); err != nil {
-				return fmt.Errorf("eval %q: %w", expr, err)
+// readGraph parses the fixture file, canonicalizes its table names and reads the dependency graph
+func (l *Loader) readGraph(ctx context.Context) (*fixtureGraph, error) {
+	doc, err := parser.ParseDocument(l.Config.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := sortedKeys(doc.Fixtures)
+	// Tables named only in the dependencies section need their canonical names too
+	for _, t := range sortedKeys(doc.Dependencies) {
+		for _, name := range append([]string{t}, doc.Dependencies[t]...) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
 			}
 		}
+	}
+
+	resolved, err := l.Database.ResolveTables(ctx, l.DB, names)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := doc.Fixtures.Canonicalize(resolved)
+
+	foreignKeys, err := l.Database.GetDependencyGraph(ctx, l.DB)
+	if err != nil {
+		return nil, err
+	}
+	graph := mergeDependencies(foreignKeys, doc.Dependencies, resolved)
+
+	return &fixtureGraph{
+		fixtures:    fixtures,
+		doc:         doc,
+		graph:       graph,
+		foreignKeys: foreignKeys,
+	}, nil
+}
+
+func (l *Loader) insertRow(ctx context.Context, q db.Querier, table string, index int, row map[string]any) error {
+	cols := make([]string, 0, len(row))
+	for col := range row {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	// Resolve directives column by column, so a directive sees the values resolved before it
+	processedRow := make(map[string]any, len(row))
+	for col, val := range row {
+		processedRow[col] = val
+	}
+
+	for _, col := range cols {
+		name, arg, ok := directive.Parse(row[col])
+		if !ok {
+			continue
+		}
+
+		fn, ok := l.lookupFunc(name)
+		if !ok {
+			// Not a directive we know about: keep the string as is
+			continue
+		}
+
+		val, err := fn(ctx, directive.Args{
+			Name:   name,
+			Arg:    arg,
+			Table:  table,
+			Column: col,
+			Index:  index,
+			Row:    processedRow,
+			Tx:     q,
+			DryRun: l.Config.DryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("%s, column %q: $%s(%s): %w", rowLocation(index, row), col, name, arg, err)
+		}
 		processedRow[col] = val
 	}
 
-	return l.Database.InsertRow(ctx, tx, table, processedRow, l.Config.DryRun)
+	for _, col := range cols {
+		var column *db.Column
+		if c, ok := l.columns.Column(table, col); ok {
+			column = &c
+		}
+		if column != nil && column.Generated {
+			// Only reachable with StrictGenerated, otherwise such values are dropped up front
+			return fmt.Errorf("%s, column %q: value for generated column", rowLocation(index, row), col)
+		}
+
+		val, err := db.ConvertValue(processedRow[col], column)
+		if err != nil {
+			return fmt.Errorf("%s, column %q: %w", rowLocation(index, row), col, err)
+		}
+		processedRow[col] = val
+	}
+
+	if err := l.postpone(table, index, row, processedRow); err != nil {
+		return err
+	}
+
+	return l.Database.InsertRow(ctx, q, table, processedRow, l.columns[table], l.Config.DryRun)
 }
 
-func (l *Loader) resetSequences(ctx context.Context, tx *sql.Tx, tables []string) error {
-	return l.Database.ResetSequences(ctx, tx, tables, l.Config.DryRun)
+// newFaker creates the faker for Config.Seed. A random seed is logged, so a failing run
+// can be reproduced with it.
+func (l *Loader) newFaker() *faker.Faker {
+	f := faker.New(l.Config.Seed)
+	if l.Config.Seed == 0 {
+		log.Printf("[seed] %d (random, pass it as the seed to reproduce the fake values)", f.Seed())
+	}
+
+	return f
+}
+
+// rowLocation describes a row for error messages, e.g. "row #3 (id=5)"
+func rowLocation(index int, row map[string]any) string {
+	if id, ok := row["id"]; ok {
+		return fmt.Sprintf("row #%d (id=%v)", index+1, id)
+	}
+
+	return fmt.Sprintf("row #%d", index+1)
+}
+
+func (l *Loader) now() time.Time {
+	if l.Config.Now != nil {
+		return l.Config.Now()
+	}
+
+	return time.Now()
+}
+
+func (l *Loader) resetSequences(ctx context.Context, q db.Querier, tables []string) error {
+	resets, err := l.Database.ResetSequences(ctx, q, tables, l.Config.DryRun)
+	if err != nil {
+		return fmt.Errorf("reset sequences: %w", err)
+	}
+
+	for _, reset := range resets {
+		log.Println("[reset-seq]", reset)
+	}
+
+	return nil
 }
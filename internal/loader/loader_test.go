@@ -3,18 +3,25 @@ package loader
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/directive"
 )
 
 // MockDatabase is a mock for the db.Database interface
 type MockDatabase struct {
 	mock.Mock
+	// implicitCommits is returned by ImplicitCommits, which is called on every load
+	implicitCommits db.ImplicitCommits
 }
 
 func (m *MockDatabase) GetDependencyGraph(ctx context.Context, d *sql.DB) (map[string][]string, error) {
@@ -22,18 +29,75 @@ func (m *MockDatabase) GetDependencyGraph(ctx context.Context, d *sql.DB) (map[s
 	return args.Get(0).(map[string][]string), args.Error(1)
 }
 
-func (m *MockDatabase) TruncateTables(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error {
-	args := m.Called(ctx, tx, tables, dryRun)
+func (m *MockDatabase) GetForeignKeys(ctx context.Context, d *sql.DB, tables []string) ([]db.ForeignKey, error) {
+	args := m.Called(ctx, d, tables)
+	return args.Get(0).([]db.ForeignKey), args.Error(1)
+}
+
+func (m *MockDatabase) ResolveTables(ctx context.Context, d *sql.DB, tables []string) (map[string]string, error) {
+	args := m.Called(ctx, d, tables)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *MockDatabase) GetColumns(ctx context.Context, d *sql.DB, tables []string) (db.Catalog, error) {
+	args := m.Called(ctx, d, tables)
+	return args.Get(0).(db.Catalog), args.Error(1)
+}
+
+func (m *MockDatabase) TruncateTables(ctx context.Context, q db.Querier, tables []string, dryRun bool) error {
+	args := m.Called(ctx, q, tables, dryRun)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) CleanupStatements(tables []string) []string {
+	args := m.Called(tables)
+	return args.Get(0).([]string)
+}
+
+func (m *MockDatabase) DeferConstraints(ctx context.Context, q db.Querier, dryRun bool) error {
+	args := m.Called(ctx, q, dryRun)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) InsertRow(ctx context.Context, q db.Querier, table string, row map[string]any, columns map[string]db.Column, dryRun bool) error {
+	args := m.Called(ctx, q, table, row, columns, dryRun)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) UpdateRow(ctx context.Context, q db.Querier, table string, key, values map[string]any, dryRun bool) error {
+	args := m.Called(ctx, q, table, key, values, dryRun)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) ResetSequences(ctx context.Context, q db.Querier, tables []string, dryRun bool) ([]db.SequenceReset, error) {
+	args := m.Called(ctx, q, tables, dryRun)
+	resets, _ := args.Get(0).([]db.SequenceReset)
+	return resets, args.Error(1)
+}
+
+func (m *MockDatabase) OwnedSequences(ctx context.Context, d *sql.DB, tables []string) ([]db.SequenceReset, error) {
+	args := m.Called(ctx, d, tables)
+	owned, _ := args.Get(0).([]db.SequenceReset)
+	return owned, args.Error(1)
+}
+
+func (m *MockDatabase) ResolveSequence(ctx context.Context, d *sql.DB, name string) (db.SequenceReset, error) {
+	args := m.Called(ctx, d, name)
+	return args.Get(0).(db.SequenceReset), args.Error(1)
+}
+
+func (m *MockDatabase) SetSequence(ctx context.Context, q db.Querier, seq db.SequenceReset, dryRun bool) error {
+	args := m.Called(ctx, q, seq, dryRun)
 	return args.Error(0)
 }
 
-func (m *MockDatabase) InsertRow(ctx context.Context, tx *sql.Tx, table string, row map[string]any, dryRun bool) error {
-	args := m.Called(ctx, tx, table, row, dryRun)
+func (m *MockDatabase) SetTimeouts(ctx context.Context, q db.Querier, timeouts db.Timeouts, dryRun bool) error {
+	args := m.Called(ctx, q, timeouts, dryRun)
 	return args.Error(0)
 }
 
-func (m *MockDatabase) ResetSequences(ctx context.Context, tx *sql.Tx, tables []string, dryRun bool) error {
-	args := m.Called(ctx, tx, tables, dryRun)
+func (m *MockDatabase) ResetTimeouts(ctx context.Context, q db.Querier, timeouts db.Timeouts, dryRun bool) error {
+	args := m.Called(ctx, q, timeouts, dryRun)
 	return args.Error(0)
 }
 
@@ -42,40 +106,28 @@ func (m *MockDatabase) Placeholder(index int) string {
 	return args.String(0)
 }
 
-func TestConvertIntervalSyntax(t *testing.T) {
-	tests := []struct {
-		name     string
-		expr     string
-		expected string
-	}{
-		{
-			name:     "single day",
-			expr:     "SELECT NOW() - INTERVAL '1 day'",
-			expected: "SELECT NOW() - INTERVAL 1 DAY",
-		},
-		{
-			name:     "multiple months",
-			expr:     "INTERVAL '3 month'",
-			expected: "INTERVAL 3 MONTH",
-		},
-		{
-			name:     "no interval",
-			expr:     "SELECT NOW()",
-			expected: "SELECT NOW()",
-		},
-		{
-			name:     "invalid interval format",
-			expr:     "INTERVAL '1day'",
-			expected: "INTERVAL '1day'",
-		},
-	}
+func (m *MockDatabase) QuoteIdent(ident string) string {
+	args := m.Called(ident)
+	return args.String(0)
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := convertIntervalSyntax(tt.expr)
-			require.Equal(t, tt.expected, result)
-		})
-	}
+func (m *MockDatabase) IsTransient(err error) bool {
+	args := m.Called(err)
+	return args.Bool(0)
+}
+
+func (m *MockDatabase) Lock(ctx context.Context, q db.Querier, name string, wait time.Duration, dryRun bool) error {
+	args := m.Called(ctx, q, name, wait, dryRun)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) Unlock(ctx context.Context, q db.Querier, name string, dryRun bool) error {
+	args := m.Called(ctx, q, name, dryRun)
+	return args.Error(0)
+}
+
+func (m *MockDatabase) ImplicitCommits() db.ImplicitCommits {
+	return m.implicitCommits
 }
 
 func TestLoader_InsertRow(t *testing.T) {
@@ -103,9 +155,9 @@ func TestLoader_InsertRow(t *testing.T) {
 	}
 
 	m.ExpectQuery("SELECT 'test'").WillReturnRows(sqlmock.NewRows([]string{""}).AddRow("test"))
-	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", expectedRow, false).Return(nil)
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", expectedRow, mock.Anything, false).Return(nil)
 
-	err = loader.insertRow(context.Background(), tx, "users", row)
+	err = loader.insertRow(context.Background(), tx, "users", 0, row)
 	require.NoError(t, err)
 
 	require.NoError(t, m.ExpectationsWereMet())
@@ -127,9 +179,9 @@ posts:
 	err := os.WriteFile(fixturePath, []byte(fixtureData), 0644)
 	require.NoError(t, err)
 
-	db, dbMock, err := sqlmock.New()
+	sqlDB, dbMock, err := sqlmock.New()
 	require.NoError(t, err)
-	defer db.Close()
+	defer sqlDB.Close()
 
 	dbMock.ExpectBegin()
 	dbMock.ExpectCommit()
@@ -137,7 +189,7 @@ posts:
 	mockDB := &MockDatabase{}
 
 	loader := &Loader{
-		DB: db,
+		DB: sqlDB,
 		Config: LoaderConfig{
 			FilePath: fixturePath,
 			Truncate: true,
@@ -147,16 +199,25 @@ posts:
 		Database: mockDB,
 	}
 
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).Return(map[string]string{
+		"users": "users",
+		"posts": "posts",
+	}, nil)
+
 	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
 		"posts": {"users"},
 	}, nil)
 
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"posts", "users"}).Return(db.Catalog{}, nil)
+
 	mockDB.On("TruncateTables", mock.Anything, mock.Anything, []string{"posts", "users"}, false).Return(nil)
 
-	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", mock.AnythingOfType("map[string]interface {}"), false).Return(nil)
-	mockDB.On("InsertRow", mock.Anything, mock.Anything, "posts", mock.AnythingOfType("map[string]interface {}"), false).Return(nil)
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", mock.AnythingOfType("map[string]interface {}"), mock.Anything, false).Return(nil)
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "posts", mock.AnythingOfType("map[string]interface {}"), mock.Anything, false).Return(nil)
 
-	mockDB.On("ResetSequences", mock.Anything, mock.Anything, []string{"posts", "users"}, false).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, []string{"posts", "users"}, false).Return([]db.SequenceReset{
+		{Table: "users", Column: "id", Sequence: "users_id_seq", Next: 2},
+	}, nil)
 
 	err = loader.Load(context.Background())
 	require.NoError(t, err)
@@ -164,3 +225,205 @@ posts:
 	require.NoError(t, dbMock.ExpectationsWereMet())
 	mockDB.AssertExpectations(t)
 }
+
+func TestLoader_Load_ResolvesTableNames(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+posts:
+  - id: 1
+    user_id: 1
+public.users:
+  - id: 1
+'"public"."users"':
+  - id: 2
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).Return(map[string]string{
+		"posts":            "public.posts",
+		"public.users":     "public.users",
+		`"public"."users"`: "public.users",
+	}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
+		"public.posts": {"public.users"},
+	}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"public.posts", "public.users"}).Return(db.Catalog{}, nil)
+
+	var inserted []string
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, false).
+		Run(func(args mock.Arguments) {
+			inserted = append(inserted, args.String(2))
+		}).
+		Return(nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath},
+	}
+	require.NoError(t, loader.Load(context.Background()))
+
+	// Both users keys became one node that is loaded before posts
+	require.Equal(t, []string{"public.users", "public.users", "public.posts"}, inserted)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_InsertRow_Fake(t *testing.T) {
+	d, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer d.Close()
+
+	m.ExpectBegin()
+	tx, err := d.Begin()
+	require.NoError(t, err)
+
+	var inserted []map[string]any
+	mockDB := &MockDatabase{}
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", mock.Anything, mock.Anything, true).
+		Run(func(args mock.Arguments) { inserted = append(inserted, args.Get(3).(map[string]any)) }).
+		Return(nil)
+
+	row := map[string]any{
+		"id":    1,
+		"email": "$fake(email)",
+		"token": "$uuid",
+	}
+
+	for i := 0; i < 2; i++ {
+		loader := &Loader{
+			DB:       d,
+			Database: mockDB,
+			Config:   LoaderConfig{Seed: 42, DryRun: true},
+		}
+		require.NoError(t, loader.insertRow(context.Background(), tx, "users", 0, row))
+	}
+
+	require.Len(t, inserted, 2)
+	require.Equal(t, inserted[0], inserted[1])
+	require.Contains(t, inserted[0]["email"], "@")
+	require.Len(t, inserted[0]["token"], 36)
+	require.Equal(t, "$fake(email)", row["email"])
+}
+
+func TestLoader_InsertRow_CustomFunc(t *testing.T) {
+	d, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer d.Close()
+
+	m.ExpectBegin()
+	tx, err := d.Begin()
+	require.NoError(t, err)
+
+	funcs := directive.NewRegistry()
+	funcs.Register("bcrypt", func(ctx context.Context, args directive.Args) (any, error) {
+		require.Equal(t, "users", args.Table)
+		require.Equal(t, "password", args.Column)
+		require.Equal(t, 1, args.Row["id"])
+		require.Same(t, tx, args.Tx)
+
+		return "hashed:" + args.Arg, nil
+	})
+	funcs.Register("broken", func(ctx context.Context, args directive.Args) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	mockDB := &MockDatabase{}
+	loader := &Loader{
+		DB:       d,
+		Database: mockDB,
+		Funcs:    funcs,
+	}
+
+	row := map[string]any{
+		"id":       1,
+		"password": "$bcrypt(secret)",
+		"price":    "$100",
+		"note":     "$unknown(x)",
+	}
+	expectedRow := map[string]any{
+		"id":       1,
+		"password": "hashed:secret",
+		"price":    "$100",
+		"note":     "$unknown(x)",
+	}
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", expectedRow, mock.Anything, false).Return(nil)
+
+	require.NoError(t, loader.insertRow(context.Background(), tx, "users", 0, row))
+
+	err = loader.insertRow(context.Background(), tx, "users", 2, map[string]any{"id": 7, "token": "$broken(x)"})
+	require.Error(t, err)
+	require.Equal(t, `row #3 (id=7), column "token": $broken(x): boom`, err.Error())
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_InsertRow_ConvertsByColumnType(t *testing.T) {
+	d, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer d.Close()
+
+	m.ExpectBegin()
+	tx, err := d.Begin()
+	require.NoError(t, err)
+
+	mockDB := &MockDatabase{}
+	loader := &Loader{
+		DB:       d,
+		Database: mockDB,
+		columns: db.Catalog{
+			"public.users": {
+				"meta": {Name: "meta", DataType: "jsonb", UDTName: "jsonb"},
+				"tags": {Name: "tags", DataType: "ARRAY", UDTName: "_text"},
+			},
+		},
+	}
+
+	row := map[string]any{
+		"id":   1,
+		"meta": map[string]any{"theme": "dark"},
+		"tags": []any{"a", "b"},
+	}
+	expectedRow := map[string]any{
+		"id":   1,
+		"meta": `{"theme":"dark"}`,
+		"tags": `{"a","b"}`,
+	}
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "public.users", expectedRow, mock.Anything, false).Return(nil)
+
+	require.NoError(t, loader.insertRow(context.Background(), tx, "public.users", 0, row))
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_InsertRow_CoercionError(t *testing.T) {
+	d, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer d.Close()
+
+	m.ExpectBegin()
+	tx, err := d.Begin()
+	require.NoError(t, err)
+
+	loader := &Loader{
+		DB:       d,
+		Database: &MockDatabase{},
+		columns: db.Catalog{
+			"public.products": {
+				"price": {Name: "price", DataType: "numeric", UDTName: "numeric"},
+			},
+		},
+	}
+
+	err = loader.insertRow(context.Background(), tx, "public.products", 1, map[string]any{"id": 2, "price": "12,50"})
+	require.Error(t, err)
+	require.Equal(t, `row #2 (id=2), column "price": "12,50" is not a number`, err.Error())
+}
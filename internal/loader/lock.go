@@ -0,0 +1,88 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"time"
+)
+
+// LockConfig describes the named lock that serializes loads into one database
+type LockConfig struct {
+	// Name identifies the lock, loads using the same name run one after another (no lock if empty)
+	Name string
+	// Wait is how long a load waits for the lock before it fails (forever if zero)
+	Wait time.Duration
+}
+
+func (c LockConfig) validate() error {
+	if c.Wait < 0 {
+		return fmt.Errorf("lock wait must not be negative")
+	}
+
+	return nil
+}
+
+// withLock runs load while holding the configured lock. The lock is taken on a connection of
+// its own that stays checked out until load returns, so it covers every transaction of the load
+// and every retry, and is released by the server if the connection is lost.
+func (l *Loader) withLock(ctx context.Context, load func() error) (err error) {
+	name := l.Config.Lock.Name
+	if name == "" {
+		return load()
+	}
+	if l.Config.DryRun {
+		log.Printf("[lock] %q skipped in dry-run, nothing is written", name)
+		return load()
+	}
+
+	// Otherwise the load would wait for a connection the lock holds
+	need := l.connections() + 1
+	if limit := l.DB.Stats().MaxOpenConnections; limit > 0 && limit < need {
+		return fmt.Errorf("lock %q: needs %d open connections (one for the lock, %d for the load), the pool allows %d",
+			name, need, need-1, limit)
+	}
+
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	if err := l.Database.Lock(ctx, conn, name, l.Config.Lock.Wait, l.Config.DryRun); err != nil {
+		discard(conn)
+		return fmt.Errorf("lock %q: %w", name, err)
+	}
+	log.Printf("[lock] acquired %q after %s", name, time.Since(start).Round(time.Millisecond))
+
+	defer func() {
+		// Release the lock even if the load was cancelled
+		if unlockErr := l.Database.Unlock(context.WithoutCancel(ctx), conn, name, l.Config.DryRun); unlockErr != nil {
+			discard(conn)
+			if err == nil {
+				err = fmt.Errorf("unlock %q: %w", name, unlockErr)
+			}
+		}
+	}()
+
+	return load()
+}
+
+// connections returns how many connections the load uses at the same time. Cleaning up after
+// a failure starts only once those are released, see undo.
+func (l *Loader) connections() int {
+	if l.Config.Workers > 1 {
+		return l.Config.Workers
+	}
+
+	return 1
+}
+
+// discard closes conn instead of returning it to the pool, so a lock it may still hold ends
+// with the session
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+}
@@ -0,0 +1,103 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_Load_Lock(t *testing.T) {
+	mockDB := parallelMock()
+	recorder := &insertRecorder{MockDatabase: mockDB}
+	loader, dbMock := parallelLoader(t, recorder)
+	loader.Config.Workers = 0
+	loader.Config.Lock = LockConfig{Name: "fixtures", Wait: time.Minute}
+
+	// The lock is held on a connection of its own around the load transaction
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB.On("Lock", mock.Anything, mock.AnythingOfType("*sql.Conn"), "fixtures", time.Minute, false).Return(nil).Once()
+	mockDB.On("Unlock", mock.Anything, mock.AnythingOfType("*sql.Conn"), "fixtures", false).Return(nil).Once()
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, mock.Anything, false).Return(nil, nil)
+
+	require.NoError(t, loader.Load(context.Background()))
+	require.Len(t, recorder.inserted, 4)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNumberOfCalls(t, "Lock", 1)
+	mockDB.AssertNumberOfCalls(t, "Unlock", 1)
+}
+
+func TestLoader_Load_LockTimeout(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB})
+	loader.Config.Workers = 0
+	loader.Config.Lock = LockConfig{Name: "fixtures", Wait: time.Second}
+
+	mockDB.On("Lock", mock.Anything, mock.Anything, "fixtures", time.Second, false).
+		Return(errors.New("not acquired within 1s")).Once()
+
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, `lock "fixtures": not acquired within 1s`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	// Nothing else is read or written
+	mockDB.AssertNotCalled(t, "GetDependencyGraph", mock.Anything, mock.Anything)
+	mockDB.AssertNotCalled(t, "Unlock", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoader_Load_LockPoolTooSmall(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB})
+	loader.Config.Lock = LockConfig{Name: "fixtures"}
+	loader.DB.SetMaxOpenConns(4)
+
+	// Four workers and the lock need five connections
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, `lock "fixtures": needs 5 open connections (one for the lock, 4 for the load), the pool allows 4`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoader_Load_LockDryRun(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB})
+	loader.Config.DryRun = true
+	loader.Config.Lock = LockConfig{Name: "fixtures"}
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, true).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, mock.Anything, true).Return(nil, nil)
+
+	require.NoError(t, loader.Load(context.Background()))
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoader_Load_LockSmallestPool(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders"})
+	loader.Config.Workers = 0
+	loader.Config.Tx = TxConfig{Mode: TxNone}
+	loader.Config.Lock = LockConfig{Name: "fixtures"}
+	// One connection for the lock, one for the load and, after it failed, for the cleanup
+	loader.DB.SetMaxOpenConns(2)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+	mockDB.On("Lock", mock.Anything, mock.Anything, "fixtures", time.Duration(0), false).Return(nil).Once()
+	mockDB.On("Unlock", mock.Anything, mock.Anything, "fixtures", false).Return(nil).Once()
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	err := loadWithin(t, loader, 5*time.Second)
+	require.EqualError(t, err, `insert into "public.orders": boom`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNumberOfCalls(t, "TruncateTables", 2)
+	mockDB.AssertNumberOfCalls(t, "Unlock", 1)
+}
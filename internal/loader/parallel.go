@@ -0,0 +1,115 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/parser"
+)
+
+// loadParallel loads the tables level by level (see db.Levels), spreading the tables of
+// a level over Workers connections with one transaction per worker. A level is committed
+// only when all its workers succeeded, so the next level sees its rows; failures are
+// handled as described in loadSteps.
+func (l *Loader) loadParallel(ctx context.Context, p *prepared) error {
+	return l.loadSteps(ctx, p, db.Levels(p.deps, p.sorted), l.Config.Workers)
+}
+
+// loadLevel inserts the rows of tables that don't depend on each other, each worker in its
+// own transaction. The transactions are committed together once every table is inserted.
+// It returns the tables with rows that were committed, in level order: only some of them if
+// a commit fails.
+func (l *Loader) loadLevel(ctx context.Context, fixtures parser.Fixtures, level []string, workers int) ([]string, error) {
+	queue := make(chan string, len(level))
+	for _, table := range level {
+		if len(fixtures[table]) > 0 {
+			queue <- table
+		}
+	}
+	close(queue)
+
+	workers = min(workers, len(queue))
+	if workers == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first failure cancels the other workers, their errors only echo it
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	txs := make([]*sql.Tx, workers)
+	// loaded holds the tables of each worker's transaction
+	loaded := make([][]string, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			tx, err := l.begin(ctx)
+			if err != nil {
+				fail(err)
+				return
+			}
+			txs[w] = tx
+
+			for table := range queue {
+				if err := l.loadRows(ctx, tx, fixtures, []string{table}); err != nil {
+					fail(err)
+					return
+				}
+				loaded[w] = append(loaded[w], table)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		l.rollback(ctx, txs, firstErr)
+
+		return nil, firstErr
+	}
+
+	// Commit barrier: the next level starts only after every transaction of this one committed
+	done := map[string]bool{}
+	committed := func() []string {
+		return slices.DeleteFunc(slices.Clone(level), func(table string) bool { return !done[table] })
+	}
+	for i, tx := range txs {
+		if err := l.end(ctx, tx, nil); err != nil {
+			err = fmt.Errorf("commit: %w", err)
+			l.rollback(ctx, txs[i+1:], err)
+
+			return committed(), err
+		}
+		for _, table := range loaded[i] {
+			done[table] = true
+		}
+	}
+
+	return committed(), nil
+}
+
+// rollback ends the transactions that were started with err
+func (l *Loader) rollback(ctx context.Context, txs []*sql.Tx, err error) {
+	for _, tx := range txs {
+		if tx != nil {
+			_ = l.end(ctx, tx, err)
+		}
+	}
+}
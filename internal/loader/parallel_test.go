@@ -0,0 +1,186 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+const parallelFixtures = `
+public.orders:
+  - id: 1
+    user_id: 1
+    product_id: 1
+public.products:
+  - id: 1
+public.users:
+  - id: 1
+  - id: 2
+`
+
+// orders depends on users and products, which are independent of each other
+func parallelMock() *MockDatabase {
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).Return(map[string]string{
+		"public.orders": "public.orders", "public.products": "public.products", "public.users": "public.users",
+	}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
+		"public.orders": {"public.users", "public.products"},
+	}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, mock.Anything).Return(db.Catalog{}, nil)
+
+	return mockDB
+}
+
+// insertRecorder records inserts itself: the mock would format the transactions of
+// the other workers while they are in use
+type insertRecorder struct {
+	*MockDatabase
+
+	mu       sync.Mutex
+	inserted []string
+	// fail is the table inserts fail for, failures limits how often (always if zero)
+	fail     string
+	failures int
+	err      error
+}
+
+func (r *insertRecorder) InsertRow(_ context.Context, _ db.Querier, table string, _ map[string]any, _ map[string]db.Column, _ bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if table == r.fail {
+		if r.failures > 0 {
+			r.failures--
+			if r.failures == 0 {
+				r.fail = ""
+			}
+		}
+		if r.err != nil {
+			return r.err
+		}
+
+		return errors.New("boom")
+	}
+	r.inserted = append(r.inserted, table)
+
+	return nil
+}
+
+func parallelLoader(t *testing.T, database db.Database) (*Loader, sqlmock.Sqlmock) {
+	t.Helper()
+
+	fixturePath := filepath.Join(t.TempDir(), "fixtures.yml")
+	require.NoError(t, os.WriteFile(fixturePath, []byte(parallelFixtures), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	// Workers begin and commit in any order
+	dbMock.MatchExpectationsInOrder(false)
+
+	return &Loader{
+		DB:       sqlDB,
+		Database: database,
+		Config:   LoaderConfig{FilePath: fixturePath, Truncate: true, ResetSeq: true, Workers: 4},
+	}, dbMock
+}
+
+func TestLoader_Load_Parallel(t *testing.T) {
+	mockDB := parallelMock()
+	recorder := &insertRecorder{MockDatabase: mockDB}
+	loader, dbMock := parallelLoader(t, recorder)
+
+	// cleanup, two workers for users and products, one for orders, sequences
+	for range 5 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+	}
+
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, mock.Anything, false).Return(nil, nil)
+
+	require.NoError(t, loader.Load(context.Background()))
+
+	require.Len(t, recorder.inserted, 4)
+	require.Equal(t, "public.orders", recorder.inserted[3])
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	// AssertExpectations would format the transactions, count the calls instead
+	mockDB.AssertNumberOfCalls(t, "TruncateTables", 1)
+	mockDB.AssertNumberOfCalls(t, "ResetSequences", 1)
+}
+
+func TestLoader_Load_ParallelFailureCleansTables(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders"})
+
+	// cleanup, the committed first level, the failed second level, cleanup again
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+	for range 2 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+	}
+	dbMock.ExpectBegin()
+	dbMock.ExpectRollback()
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, `level 2: insert into "public.orders": boom`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNumberOfCalls(t, "TruncateTables", 2)
+}
+
+// spreadInserts makes every worker of the first level wait in its first insert until the other
+// worker started too, so each transaction holds one table
+type spreadInserts struct {
+	*insertRecorder
+	started sync.WaitGroup
+	seen    sync.Map
+}
+
+func (s *spreadInserts) InsertRow(ctx context.Context, q db.Querier, table string, row map[string]any, columns map[string]db.Column, dryRun bool) error {
+	if _, seen := s.seen.LoadOrStore(table, true); !seen && table != "public.orders" {
+		s.started.Done()
+		s.started.Wait()
+	}
+
+	return s.insertRecorder.InsertRow(ctx, q, table, row, columns, dryRun)
+}
+
+func TestLoader_Load_ParallelCommitFailureCleansTables(t *testing.T) {
+	mockDB := parallelMock()
+	spread := &spreadInserts{insertRecorder: &insertRecorder{MockDatabase: mockDB}}
+	spread.started.Add(2)
+	loader, dbMock := parallelLoader(t, spread)
+
+	// cleanup, the first level where the second commit fails after the first one went through,
+	// cleanup again
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+	dbMock.ExpectBegin()
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+	dbMock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, "level 1: commit: connection reset")
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNumberOfCalls(t, "TruncateTables", 2)
+}
@@ -0,0 +1,174 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+// Plan describes what Load would do with the current fixtures and schema
+type Plan struct {
+	// Tables are in load order, including parents that are only cleaned
+	Tables       []PlanTable      `json:"tables"`
+	Dependencies []PlanDependency `json:"dependencies"`
+	// Cleanup holds the statements run before loading, empty without Truncate
+	Cleanup []string `json:"cleanup"`
+	// DeferredConstraints is set when a cycle is broken by checking constraints at commit
+	DeferredConstraints bool `json:"deferred_constraints"`
+	// Postponed lists per table the foreign key columns set by UPDATE after all rows exist
+	Postponed map[string][]string `json:"postponed,omitempty"`
+	// ResetSequences lists the sequences (AUTO_INCREMENT columns) moved past the loaded rows,
+	// empty without ResetSeq
+	ResetSequences []PlanSequence `json:"reset_sequences"`
+	// Sequences holds the values from the sequences section, applied last
+	Sequences []db.SequenceReset `json:"sequences"`
+}
+
+// PlanTable is a table with the number of rows loaded into it
+type PlanTable struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`
+}
+
+// PlanSequence is a sequence or AUTO_INCREMENT column of a loaded table
+type PlanSequence struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// Sequence is the sequence name, empty for AUTO_INCREMENT
+	Sequence string `json:"sequence,omitempty"`
+}
+
+// String returns e.g. "public.users.id (public.users_id_seq)"
+func (s PlanSequence) String() string {
+	counter := s.Sequence
+	if counter == "" {
+		counter = "AUTO_INCREMENT"
+	}
+
+	return fmt.Sprintf("%s.%s (%s)", s.Table, s.Column, counter)
+}
+
+// PlanDependency is an edge of the dependency graph: Table is loaded after DependsOn
+type PlanDependency struct {
+	Table     string `json:"table"`
+	DependsOn string `json:"depends_on"`
+	// Declared is set for dependencies from the fixture file rather than a foreign key
+	Declared bool `json:"declared"`
+}
+
+// Plan resolves the fixtures against the database like Load does, without writing anything
+func (l *Loader) Plan(ctx context.Context) (*Plan, error) {
+	p, err := l.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Tables:              make([]PlanTable, 0, len(p.sorted)),
+		Dependencies:        []PlanDependency{},
+		Cleanup:             []string{},
+		DeferredConstraints: p.cycles.deferConstraints,
+		ResetSequences:      []PlanSequence{},
+		Sequences:           p.sequences,
+	}
+	if plan.Sequences == nil {
+		plan.Sequences = []db.SequenceReset{}
+	}
+
+	for i := len(p.sorted) - 1; i >= 0; i-- {
+		table := p.sorted[i]
+		plan.Tables = append(plan.Tables, PlanTable{Name: table, Rows: len(p.fixtures[table])})
+
+		parents := slices.Clone(p.graph[table])
+		slices.Sort(parents)
+		for _, parent := range parents {
+			plan.Dependencies = append(plan.Dependencies, PlanDependency{
+				Table:     table,
+				DependsOn: parent,
+				Declared:  !slices.Contains(p.foreignKeys[table], parent),
+			})
+		}
+	}
+
+	if len(p.cycles.nullFirst) > 0 {
+		plan.Postponed = p.cycles.nullFirst
+	}
+
+	if l.Config.Truncate && len(p.sorted) > 0 {
+		plan.Cleanup = l.Database.CleanupStatements(p.sorted)
+	}
+	if l.Config.ResetSeq {
+		owned, err := l.Database.OwnedSequences(ctx, l.DB, p.loadOrder())
+		if err != nil {
+			return nil, err
+		}
+		for _, seq := range owned {
+			plan.ResetSequences = append(plan.ResetSequences, PlanSequence{Table: seq.Table, Column: seq.Column, Sequence: seq.Sequence})
+		}
+	}
+
+	return plan, nil
+}
+
+// WriteText prints the plan for humans
+func (p *Plan) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("Load order:\n")
+	for i, t := range p.Tables {
+		fmt.Fprintf(&b, "  %d. %s: %s\n", i+1, t.Name, pluralRows(t.Rows))
+	}
+
+	if len(p.Dependencies) > 0 {
+		b.WriteString("\nDependencies:\n")
+		for _, d := range p.Dependencies {
+			fmt.Fprintf(&b, "  %s -> %s", d.Table, d.DependsOn)
+			if d.Declared {
+				b.WriteString(" (declared)")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if p.DeferredConstraints || len(p.Postponed) > 0 {
+		b.WriteString("\nCycles:\n")
+		if p.DeferredConstraints {
+			b.WriteString("  DEFERRABLE constraints are checked at commit\n")
+		}
+		for _, table := range sortedKeys(p.Postponed) {
+			fmt.Fprintf(&b, "  %s: %s set by UPDATE after all rows\n", table, strings.Join(p.Postponed[table], ", "))
+		}
+	}
+
+	if len(p.Cleanup) > 0 {
+		b.WriteString("\nCleanup:\n")
+		for _, stmt := range p.Cleanup {
+			fmt.Fprintf(&b, "  %s\n", stmt)
+		}
+	}
+
+	if len(p.ResetSequences) > 0 || len(p.Sequences) > 0 {
+		b.WriteString("\nSequences:\n")
+		for _, seq := range p.ResetSequences {
+			fmt.Fprintf(&b, "  %s: reset past the loaded rows\n", seq)
+		}
+		for _, seq := range p.Sequences {
+			fmt.Fprintf(&b, "  %s\n", seq)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func pluralRows(n int) string {
+	if n == 1 {
+		return "1 row"
+	}
+
+	return fmt.Sprintf("%d rows", n)
+}
@@ -0,0 +1,114 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+func TestLoader_Plan(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+dependencies:
+  public.audit_log: [public.users]
+sequences:
+  public.users.id: 100
+public.orders:
+  - id: 1
+    user_id: 1
+public.users:
+  - id: 1
+  - id: 2
+public.audit_log:
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).Return(map[string]string{
+		"public.audit_log": "public.audit_log", "public.orders": "public.orders", "public.users": "public.users",
+	}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
+		"public.orders": {"public.users"},
+		"public.users":  {"public.accounts"},
+	}, nil)
+	sorted := []string{"public.orders", "public.audit_log", "public.users", "public.accounts"}
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, sorted).Return(db.Catalog{}, nil)
+	mockDB.On("ResolveSequence", mock.Anything, mock.Anything, "public.users.id").
+		Return(db.SequenceReset{Table: "public.users", Column: "id", Sequence: "users_id_seq"}, nil)
+	mockDB.On("CleanupStatements", sorted).Return([]string{"TRUNCATE ..."})
+	// Read from the catalog in load order, without touching the values
+	mockDB.On("OwnedSequences", mock.Anything, mock.Anything,
+		[]string{"public.accounts", "public.users", "public.audit_log", "public.orders"}).
+		Return([]db.SequenceReset{
+			{Table: "public.users", Column: "id", Sequence: "users_id_seq"},
+			{Table: "public.orders", Column: "id", Sequence: "orders_id_seq"},
+		}, nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath, Truncate: true, ResetSeq: true},
+	}
+	plan, err := loader.Plan(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, &Plan{
+		Tables: []PlanTable{
+			{Name: "public.accounts", Rows: 0},
+			{Name: "public.users", Rows: 2},
+			{Name: "public.audit_log", Rows: 1},
+			{Name: "public.orders", Rows: 1},
+		},
+		Dependencies: []PlanDependency{
+			{Table: "public.users", DependsOn: "public.accounts"},
+			{Table: "public.audit_log", DependsOn: "public.users", Declared: true},
+			{Table: "public.orders", DependsOn: "public.users"},
+		},
+		Cleanup: []string{"TRUNCATE ..."},
+		ResetSequences: []PlanSequence{
+			{Table: "public.users", Column: "id", Sequence: "users_id_seq"},
+			{Table: "public.orders", Column: "id", Sequence: "orders_id_seq"},
+		},
+		Sequences: []db.SequenceReset{{Table: "public.users", Column: "id", Sequence: "users_id_seq", Next: 100}},
+	}, plan)
+
+	var out strings.Builder
+	require.NoError(t, plan.WriteText(&out))
+	require.Equal(t, `Load order:
+  1. public.accounts: 0 rows
+  2. public.users: 2 rows
+  3. public.audit_log: 1 row
+  4. public.orders: 1 row
+
+Dependencies:
+  public.users -> public.accounts
+  public.audit_log -> public.users (declared)
+  public.orders -> public.users
+
+Cleanup:
+  TRUNCATE ...
+
+Sequences:
+  public.users.id (users_id_seq): reset past the loaded rows
+  public.orders.id (orders_id_seq): reset past the loaded rows
+  public.users.id (users_id_seq): next value 100
+`, out.String())
+
+	// Nothing was written
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,124 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// RetryConfig describes how a load copes with a database that isn't ready yet and with
+// transient failures (see db.Database.IsTransient)
+type RetryConfig struct {
+	// Attempts is how many times a load failing with a transient error is tried (once if zero)
+	Attempts int
+	// WaitTimeout keeps pinging the database before the load until it answers or the time is up
+	// (no waiting if zero)
+	WaitTimeout time.Duration
+	// Backoff is the pause after the first failure, doubled after each next one up to MaxBackoff
+	// (100ms and 5s if zero)
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (c RetryConfig) validate() error {
+	if c.Attempts < 0 || c.WaitTimeout < 0 || c.Backoff < 0 || c.MaxBackoff < 0 {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+
+	return nil
+}
+
+// backoff returns the pause after the given failed attempt (1 for the first)
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	d, maxD := c.Backoff, c.MaxBackoff
+	if d == 0 {
+		d = defaultBackoff
+	}
+	if maxD == 0 {
+		maxD = defaultMaxBackoff
+	}
+
+	for i := 1; i < attempt && d < maxD; i++ {
+		d *= 2
+	}
+
+	return min(d, maxD)
+}
+
+// waitForDB pings the database until it answers or WaitTimeout has passed
+func (l *Loader) waitForDB(ctx context.Context) error {
+	if l.Config.Retry.WaitTimeout == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(l.Config.Retry.WaitTimeout)
+	for attempt := 1; ; attempt++ {
+		err := l.DB.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		pause := l.Config.Retry.backoff(attempt)
+		if ctx.Err() != nil || time.Now().Add(pause).After(deadline) {
+			return fmt.Errorf("database not ready after %s: %w", l.Config.Retry.WaitTimeout, err)
+		}
+
+		log.Printf("[wait] database not ready (attempt %d), next try in %s: %v", attempt, pause, err)
+		if err := sleep(ctx, pause); err != nil {
+			return err
+		}
+	}
+}
+
+// retry runs load until it succeeds, fails with an error that isn't transient or runs out
+// of attempts. retryable is false when a failed attempt may leave rows behind that another
+// attempt would insert again.
+func (l *Loader) retry(ctx context.Context, retryable bool, load func() error) error {
+	attempts := max(l.Config.Retry.Attempts, 1)
+	if !retryable {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := load()
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("[retry] attempt %d/%d succeeded", attempt, attempts)
+			}
+
+			return nil
+		}
+
+		if attempts == 1 || !l.Database.IsTransient(err) {
+			return err
+		}
+		if attempt == attempts {
+			return fmt.Errorf("%w (gave up after %d attempts)", err, attempts)
+		}
+
+		pause := l.Config.Retry.backoff(attempt)
+		log.Printf("[retry] attempt %d/%d failed with a transient error, next try in %s: %v", attempt, attempts, pause, err)
+		if err := sleep(ctx, pause); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep pauses for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
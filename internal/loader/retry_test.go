@@ -0,0 +1,177 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+var errDeadlock = errors.New("deadlock detected")
+
+func TestLoader_Load_RetryTransient(t *testing.T) {
+	mockDB := parallelMock()
+	recorder := &insertRecorder{MockDatabase: mockDB, fail: "public.orders", failures: 1, err: errDeadlock}
+	loader, dbMock := parallelLoader(t, recorder)
+	loader.Config.Workers = 0
+	loader.Config.Retry = RetryConfig{Attempts: 3, Backoff: time.Millisecond}
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectRollback()
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB.On("IsTransient", mock.MatchedBy(func(err error) bool { return errors.Is(err, errDeadlock) })).Return(true)
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, mock.Anything, false).Return(nil, nil)
+
+	require.NoError(t, loader.Load(context.Background()))
+	// The three rows before the failure were rolled back, the second attempt inserts all four
+	require.Len(t, recorder.inserted, 7)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNumberOfCalls(t, "TruncateTables", 2)
+}
+
+func TestLoader_Load_RetryGivesUp(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders", err: errDeadlock})
+	loader.Config.Workers = 0
+	loader.Config.Retry = RetryConfig{Attempts: 2, Backoff: time.Millisecond}
+
+	for range 2 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectRollback()
+	}
+
+	mockDB.On("IsTransient", mock.Anything).Return(true)
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, `insert into "public.orders": deadlock detected (gave up after 2 attempts)`)
+	require.ErrorIs(t, err, errDeadlock)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestLoader_Load_RetryNotTransient(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders"})
+	loader.Config.Workers = 0
+	loader.Config.Retry = RetryConfig{Attempts: 3, Backoff: time.Millisecond}
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectRollback()
+
+	mockDB.On("IsTransient", mock.Anything).Return(false)
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	require.EqualError(t, loader.Load(context.Background()), `insert into "public.orders": boom`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestLoader_Load_RetryLeavesCommittedRows(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders", err: errDeadlock})
+	loader.Config.Workers = 0
+	loader.Config.Truncate = false
+	loader.Config.Tx = TxConfig{Mode: TxPerTable}
+	loader.Config.Retry = RetryConfig{Attempts: 3, Backoff: time.Millisecond}
+
+	// Another attempt would insert the committed rows again: no retry, IsTransient isn't asked
+	for range 2 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+	}
+	dbMock.ExpectBegin()
+	dbMock.ExpectRollback()
+
+	err := loader.Load(context.Background())
+	require.ErrorIs(t, err, errDeadlock)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNotCalled(t, "IsTransient", mock.Anything)
+}
+
+func TestLoader_Load_RetryImplicitCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		commits  db.ImplicitCommits
+		truncate bool
+		resetSeq bool
+		attempts int
+	}{
+		{name: "sequences after commit", commits: db.ImplicitCommits{Sequences: true}, resetSeq: true, attempts: 1},
+		{name: "no sequences to update", commits: db.ImplicitCommits{Sequences: true}, attempts: 2},
+		{name: "sequences with truncate", commits: db.ImplicitCommits{Sequences: true}, truncate: true, resetSeq: true, attempts: 2},
+		{name: "fast truncate", commits: db.ImplicitCommits{Cleanup: true}, truncate: true, attempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := parallelMock()
+			mockDB.implicitCommits = tt.commits
+			loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders", err: errDeadlock})
+			loader.Config.Workers = 0
+			loader.Config.Truncate = tt.truncate
+			loader.Config.ResetSeq = tt.resetSeq
+			loader.Config.Retry = RetryConfig{Attempts: 2, Backoff: time.Millisecond}
+
+			for range tt.attempts {
+				dbMock.ExpectBegin()
+				dbMock.ExpectRollback()
+			}
+			mockDB.On("IsTransient", mock.Anything).Return(true)
+			mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+			require.ErrorIs(t, loader.Load(context.Background()), errDeadlock)
+			require.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoader_WaitForDB(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	dbMock.ExpectPing().WillReturnError(errors.New("the database system is starting up"))
+	dbMock.ExpectPing()
+
+	loader := &Loader{
+		DB:     sqlDB,
+		Config: LoaderConfig{Retry: RetryConfig{WaitTimeout: time.Second, Backoff: time.Millisecond}},
+	}
+	require.NoError(t, loader.waitForDB(context.Background()))
+	require.NoError(t, dbMock.ExpectationsWereMet())
+
+	// Gives up once the next pause would pass the deadline
+	dbMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	loader.Config.Retry = RetryConfig{WaitTimeout: 10 * time.Millisecond, Backoff: time.Second}
+	require.EqualError(t, loader.waitForDB(context.Background()), "database not ready after 10ms: connection refused")
+}
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RetryConfig
+		attempt int
+		want    time.Duration
+	}{
+		{name: "default", attempt: 1, want: 100 * time.Millisecond},
+		{name: "doubled", attempt: 3, want: 400 * time.Millisecond},
+		{name: "default max", attempt: 20, want: 5 * time.Second},
+		{name: "custom", config: RetryConfig{Backoff: time.Second, MaxBackoff: 3 * time.Second}, attempt: 2, want: 2 * time.Second},
+		{name: "custom max", config: RetryConfig{Backoff: time.Second, MaxBackoff: 3 * time.Second}, attempt: 3, want: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.config.backoff(tt.attempt))
+		})
+	}
+}
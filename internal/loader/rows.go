@@ -0,0 +1,157 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/parser"
+)
+
+// orderSelfReferencing sorts the rows of tables with a foreign key to themselves
+// (categories, org charts, comment threads), so that every row is inserted after
+// the row it references
+func (l *Loader) orderSelfReferencing(ctx context.Context, fixtures parser.Fixtures, selfRefs map[string]bool) error {
+	var tables []string
+	for _, table := range sortedKeys(selfRefs) {
+		if len(fixtures[table]) > 1 {
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	keys, err := l.Database.GetForeignKeys(ctx, l.DB, tables)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		var selfKeys []db.ForeignKey
+		for _, fk := range keys {
+			if fk.Table == table && fk.IsSelfReference() {
+				selfKeys = append(selfKeys, fk)
+			}
+		}
+
+		rows, err := orderRows(fixtures[table], selfKeys)
+		if err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+		fixtures[table] = rows
+	}
+
+	return nil
+}
+
+// orderRows puts referenced rows before the rows referencing them and keeps the fixture
+// order otherwise. References to rows that are not in the fixtures are left to the database.
+func orderRows(rows []map[string]any, keys []db.ForeignKey) ([]map[string]any, error) {
+	// deps[i] lists the rows referenced by row i
+	deps := make([][]int, len(rows))
+	for _, fk := range keys {
+		byKey := make(map[string]int, len(rows))
+		for i, row := range rows {
+			if key, ok := rowKey(row, fk.RefColumns); ok {
+				byKey[key] = i
+			}
+		}
+
+		for i, row := range rows {
+			key, ok := rowKey(row, fk.Columns)
+			if !ok {
+				continue
+			}
+			// A row referencing itself is fine for the database
+			if j, found := byKey[key]; found && j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(rows))
+	order := make([]map[string]any, 0, len(rows))
+	var path []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return rowCycleError(rows, keys, path, i)
+		}
+
+		state[i] = visiting
+		path = append(path, i)
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+
+		order = append(order, rows[i])
+
+		return nil
+	}
+
+	for i := range rows {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// rowKey joins the values of columns, ok is false if any of them is missing or null
+func rowKey(row map[string]any, columns []string) (string, bool) {
+	parts := make([]string, 0, len(columns))
+	for _, col := range columns {
+		val, ok := row[col]
+		if !ok || val == nil {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprint(val))
+	}
+
+	return strings.Join(parts, "\x00"), true
+}
+
+// rowCycleError reports the rows on the path from start back to start, e.g. "id=1 -> id=2 -> id=1"
+func rowCycleError(rows []map[string]any, keys []db.ForeignKey, path []int, start int) error {
+	var cycle []string
+	for k := len(path) - 1; k >= 0; k-- {
+		if path[k] == start {
+			for _, i := range path[k:] {
+				cycle = append(cycle, describeRow(rows[i], keys[0].RefColumns))
+			}
+			break
+		}
+	}
+	cycle = append(cycle, describeRow(rows[start], keys[0].RefColumns))
+
+	return fmt.Errorf("rows reference each other in a cycle: %s", strings.Join(cycle, " -> "))
+}
+
+// describeRow names a row by its key columns, e.g. "id=1" or "(tenant_id=1, id=2)"
+func describeRow(row map[string]any, columns []string) string {
+	parts := make([]string, 0, len(columns))
+	for _, col := range columns {
+		parts = append(parts, fmt.Sprintf("%s=%v", col, row[col]))
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,158 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+func TestOrderRows(t *testing.T) {
+	parentKey := []db.ForeignKey{{
+		Table: "categories", Columns: []string{"parent_id"}, RefTable: "categories", RefColumns: []string{"id"},
+	}}
+
+	tests := []struct {
+		name     string
+		rows     []map[string]any
+		keys     []db.ForeignKey
+		expected []map[string]any
+		err      string
+	}{
+		{
+			name: "children before parents",
+			rows: []map[string]any{
+				{"id": 3, "parent_id": 2},
+				{"id": 2, "parent_id": 1},
+				{"id": 1, "parent_id": nil},
+				{"id": 4},
+			},
+			keys: parentKey,
+			expected: []map[string]any{
+				{"id": 1, "parent_id": nil},
+				{"id": 2, "parent_id": 1},
+				{"id": 3, "parent_id": 2},
+				{"id": 4},
+			},
+		},
+		{
+			name: "already ordered rows keep their order",
+			rows: []map[string]any{
+				{"id": 1},
+				{"id": 2, "parent_id": 1},
+				{"id": 3, "parent_id": 1},
+			},
+			keys: parentKey,
+			expected: []map[string]any{
+				{"id": 1},
+				{"id": 2, "parent_id": 1},
+				{"id": 3, "parent_id": 1},
+			},
+		},
+		{
+			name: "references outside the fixtures and to the row itself",
+			rows: []map[string]any{
+				{"id": 2, "parent_id": 100},
+				{"id": 1, "parent_id": 1},
+			},
+			keys: parentKey,
+			expected: []map[string]any{
+				{"id": 2, "parent_id": 100},
+				{"id": 1, "parent_id": 1},
+			},
+		},
+		{
+			name: "composite key",
+			rows: []map[string]any{
+				{"tenant_id": 1, "id": 2, "parent_id": 1},
+				{"tenant_id": 2, "id": 1},
+				{"tenant_id": 1, "id": 1},
+			},
+			keys: []db.ForeignKey{{
+				Table: "nodes", Columns: []string{"tenant_id", "parent_id"}, RefTable: "nodes", RefColumns: []string{"tenant_id", "id"},
+			}},
+			expected: []map[string]any{
+				{"tenant_id": 1, "id": 1},
+				{"tenant_id": 1, "id": 2, "parent_id": 1},
+				{"tenant_id": 2, "id": 1},
+			},
+		},
+		{
+			name: "cycle",
+			rows: []map[string]any{
+				{"id": 1, "parent_id": 3},
+				{"id": 2, "parent_id": 1},
+				{"id": 3, "parent_id": 2},
+			},
+			keys: parentKey,
+			err:  "rows reference each other in a cycle: id=1 -> id=3 -> id=2 -> id=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := orderRows(tt.rows, tt.keys)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, rows)
+		})
+	}
+}
+
+func TestLoader_Load_SelfReferencingTable(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+public.categories:
+  - id: 2
+    parent_id: 1
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).
+		Return(map[string]string{"public.categories": "public.categories"}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{
+		"public.categories": {"public.categories"},
+	}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"public.categories"}).Return(db.Catalog{}, nil)
+	mockDB.On("GetForeignKeys", mock.Anything, mock.Anything, []string{"public.categories"}).Return([]db.ForeignKey{{
+		Name: "fk_parent", Table: "public.categories", Columns: []string{"parent_id"},
+		RefTable: "public.categories", RefColumns: []string{"id"},
+	}}, nil)
+
+	var ids []any
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "public.categories", mock.Anything, mock.Anything, false).
+		Run(func(args mock.Arguments) {
+			ids = append(ids, args.Get(3).(map[string]any)["id"])
+		}).
+		Return(nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath},
+	}
+	require.NoError(t, loader.Load(context.Background()))
+
+	require.Equal(t, []any{1, 2}, ids)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,45 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+// resolveSequences checks the sequences section against the catalog before anything is written
+func (l *Loader) resolveSequences(ctx context.Context, sequences map[string]int64) ([]db.SequenceReset, error) {
+	var resolved []db.SequenceReset
+	var errs []error
+	for _, name := range sortedKeys(sequences) {
+		seq, err := l.Database.ResolveSequence(ctx, l.DB, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		seq.Next = sequences[name]
+		resolved = append(resolved, seq)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("sequences check failed (%d problems):\n%w", len(errs), errors.Join(errs...))
+	}
+
+	return resolved, nil
+}
+
+// setSequences applies the explicit sequence values, after the automatic reset
+func (l *Loader) setSequences(ctx context.Context, q db.Querier, sequences []db.SequenceReset) error {
+	for _, seq := range sequences {
+		if err := l.Database.SetSequence(ctx, q, seq, l.Config.DryRun); err != nil {
+			return fmt.Errorf("set sequence %s: %w", seq, err)
+		}
+
+		log.Println("[set-seq]", seq)
+	}
+
+	return nil
+}
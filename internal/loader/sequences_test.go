@@ -0,0 +1,107 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+func TestLoader_Load_Sequences(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+sequences:
+  public.users.id: 1000
+public.users:
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	var calls []string
+	record := func(args mock.Arguments) { calls = append(calls, "reset") }
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).
+		Return(map[string]string{"public.users": "public.users"}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"public.users"}).Return(db.Catalog{}, nil)
+	mockDB.On("ResolveSequence", mock.Anything, mock.Anything, "public.users.id").
+		Return(db.SequenceReset{Table: "public.users", Column: "id", Sequence: "users_id_seq"}, nil)
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "public.users", mock.Anything, mock.Anything, false).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, []string{"public.users"}, false).
+		Run(record).
+		Return([]db.SequenceReset{{Table: "public.users", Column: "id", Sequence: "users_id_seq", Next: 2}}, nil)
+	mockDB.On("SetSequence", mock.Anything, mock.Anything,
+		db.SequenceReset{Table: "public.users", Column: "id", Sequence: "users_id_seq", Next: 1000}, false).
+		Run(func(args mock.Arguments) { calls = append(calls, "set") }).
+		Return(nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath, ResetSeq: true},
+	}
+	require.NoError(t, loader.Load(context.Background()))
+
+	// The explicit value is applied after the automatic reset
+	require.Equal(t, []string{"reset", "set"}, calls)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_Load_UnknownSequences(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	fixtureData := `
+sequences:
+  public.users.name: 10
+  public.nope_seq: 20
+public.users:
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(fixturePath, []byte(fixtureData), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, mock.Anything).
+		Return(map[string]string{"public.users": "public.users"}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"public.users"}).Return(db.Catalog{}, nil)
+	mockDB.On("ResolveSequence", mock.Anything, mock.Anything, "public.nope_seq").
+		Return(db.SequenceReset{}, errors.New(`unknown sequence "public.nope_seq"`))
+	mockDB.On("ResolveSequence", mock.Anything, mock.Anything, "public.users.name").
+		Return(db.SequenceReset{}, errors.New(`unknown sequence "public.users.name"`))
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config:   LoaderConfig{FilePath: fixturePath, Truncate: true},
+	}
+
+	err = loader.Load(context.Background())
+	require.EqualError(t, err, `sequences check failed (2 problems):
+unknown sequence "public.nope_seq"
+unknown sequence "public.users.name"`)
+
+	// Nothing was written
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,92 @@
+package loader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Offset components accepted by $now(...) and $today(...), e.g. "-24h", "+3d", "1w2d", "-1h30m"
+var offsetPartRe = regexp.MustCompile(`(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+
+// Layouts accepted by $time(...)
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// evalTime evaluates a time directive against the given reference time
+func evalTime(fn, arg string, now time.Time) (time.Time, error) {
+	switch fn {
+	case "now":
+		return applyOffset(now, arg)
+	case "today":
+		y, m, d := now.Date()
+
+		return applyOffset(time.Date(y, m, d, 0, 0, 0, 0, now.Location()), arg)
+	case "time":
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, arg, now.Location()); err == nil {
+				return t, nil
+			}
+		}
+
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", arg)
+	default:
+		return time.Time{}, fmt.Errorf("unknown time directive %q", fn)
+	}
+}
+
+// applyOffset shifts t by an offset such as "-24h" or "+3d".
+// Days and weeks are calendar units, everything else follows time.ParseDuration.
+func applyOffset(t time.Time, offset string) (time.Time, error) {
+	if offset == "" {
+		return t, nil
+	}
+
+	sign := 1
+	rest := offset
+	switch rest[0] {
+	case '+':
+		rest = rest[1:]
+	case '-':
+		sign = -1
+		rest = rest[1:]
+	}
+
+	if rest == "" || offsetPartRe.ReplaceAllString(rest, "") != "" {
+		return time.Time{}, fmt.Errorf("invalid offset %q", offset)
+	}
+
+	var days int
+	var clock strings.Builder
+	for _, part := range offsetPartRe.FindAllStringSubmatch(rest, -1) {
+		switch part[2] {
+		case "d", "w":
+			n, err := strconv.Atoi(part[1])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid offset %q: days and weeks must be integers", offset)
+			}
+			if part[2] == "w" {
+				n *= 7
+			}
+			days += n
+		default:
+			clock.WriteString(part[0])
+		}
+	}
+
+	var dur time.Duration
+	if clock.Len() > 0 {
+		var err error
+		if dur, err = time.ParseDuration(clock.String()); err != nil {
+			return time.Time{}, fmt.Errorf("invalid offset %q: %w", offset, err)
+		}
+	}
+
+	return t.AddDate(0, 0, sign*days).Add(time.Duration(sign) * dur), nil
+}
@@ -0,0 +1,90 @@
+package loader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		fn        string
+		arg       string
+		expected  time.Time
+		expectErr bool
+	}{
+		{name: "now", fn: "now", expected: now},
+		{name: "now minus hours", fn: "now", arg: "-24h", expected: now.Add(-24 * time.Hour)},
+		{name: "now plus days and hours", fn: "now", arg: "+1d12h", expected: now.AddDate(0, 0, 1).Add(12 * time.Hour)},
+		{name: "now minus week", fn: "now", arg: "-1w", expected: now.AddDate(0, 0, -7)},
+		{name: "today", fn: "today", expected: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{name: "today plus days", fn: "today", arg: "+3d", expected: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "time rfc3339", fn: "time", arg: "2024-01-01T00:00:00Z", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "time date only", fn: "time", arg: "2024-01-02", expected: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "invalid offset", fn: "now", arg: "-1 day", expectErr: true},
+		{name: "fractional days", fn: "now", arg: "1.5d", expectErr: true},
+		{name: "invalid timestamp", fn: "time", arg: "yesterday", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := evalTime(tt.fn, tt.arg, now)
+			if tt.expectErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, tt.expected.Equal(result), "expected %s, got %s", tt.expected, result)
+		})
+	}
+}
+
+func TestLoader_InsertRow_TimeDirectives(t *testing.T) {
+	d, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer d.Close()
+
+	m.ExpectBegin()
+	tx, err := d.Begin()
+	require.NoError(t, err)
+
+	frozen := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mockDB := &MockDatabase{}
+	loader := &Loader{
+		DB:       d,
+		Database: mockDB,
+		Config: LoaderConfig{
+			Now: func() time.Time { return frozen },
+		},
+	}
+
+	row := map[string]any{
+		"id":         1,
+		"created_at": "$now(-24h)",
+		"due_date":   "$today(+3d)",
+		"epoch":      "$time(2024-01-01T00:00:00Z)",
+	}
+	expectedRow := map[string]any{
+		"id":         1,
+		"created_at": frozen.Add(-24 * time.Hour),
+		"due_date":   time.Date(2024, 1, 18, 0, 0, 0, 0, time.UTC),
+		"epoch":      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	mockDB.On("InsertRow", mock.Anything, mock.Anything, "users", expectedRow, mock.Anything, false).Return(nil)
+
+	err = loader.insertRow(context.Background(), tx, "users", 0, row)
+	require.NoError(t, err)
+
+	require.NoError(t, m.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,256 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+// TxMode selects how a load is split into transactions
+type TxMode string
+
+const (
+	// TxSingle loads everything in one transaction, the default
+	TxSingle TxMode = "single"
+	// TxPerTable commits the cleanup, every table and the final updates separately
+	TxPerTable TxMode = "per-table"
+	// TxNone runs every statement on its own, for loads too big for one transaction
+	TxNone TxMode = "none"
+)
+
+// TxConfig describes the transactions of a load
+type TxConfig struct {
+	// Mode is TxSingle if empty
+	Mode TxMode
+	// Isolation is the isolation level of the transactions (the driver default if zero)
+	Isolation sql.IsolationLevel
+	// StatementTimeout and LockTimeout limit every statement of the load (no limit if zero)
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+}
+
+// validate checks the mode and its combination with the other settings
+func (c TxConfig) validate(workers int) error {
+	switch c.Mode {
+	case "", TxSingle, TxPerTable, TxNone:
+	default:
+		return fmt.Errorf("unknown tx mode %q (supported modes: %s, %s, %s)", c.Mode, TxSingle, TxPerTable, TxNone)
+	}
+
+	if c.Mode == TxNone && c.Isolation != sql.LevelDefault {
+		return fmt.Errorf("tx mode %q runs no transactions, an isolation level can't be set", c.Mode)
+	}
+	if workers > 1 && c.Mode != "" && c.Mode != TxSingle {
+		return fmt.Errorf("tx mode %q can't be combined with workers, which commit every level", c.Mode)
+	}
+	if c.StatementTimeout < 0 || c.LockTimeout < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+
+	return nil
+}
+
+func (c TxConfig) timeouts() db.Timeouts {
+	return db.Timeouts{Statement: c.StatementTimeout, Lock: c.LockTimeout}
+}
+
+// loadPerTable commits every table on its own, see loadSteps
+func (l *Loader) loadPerTable(ctx context.Context, p *prepared) error {
+	var steps [][]string
+	for _, table := range p.loadOrder() {
+		steps = append(steps, []string{table})
+	}
+
+	return l.loadSteps(ctx, p, steps, 1)
+}
+
+// loadWithoutTx runs the load on one connection without a transaction, so every statement
+// commits on its own. A failure after the cleanup is handled like in loadSteps.
+func (l *Loader) loadWithoutTx(ctx context.Context, p *prepared) error {
+	// left describes the rows written before a failure
+	var left string
+	err := l.withConn(ctx, func(conn *sql.Conn) error {
+		if err := l.cleanup(ctx, conn, p); err != nil {
+			return err
+		}
+
+		left = "rows inserted before the failure"
+		if err := l.loadRows(ctx, conn, p.fixtures, p.loadOrder()); err != nil {
+			return err
+		}
+		left = "all rows"
+		// Without a transaction there is nothing for an implicit commit to end early
+		if err := l.applyUpdates(ctx, conn); err != nil {
+			return err
+		}
+
+		return l.updateSequences(ctx, conn, p)
+	})
+	if err != nil {
+		// The cleanup needs a connection, so it runs once the load's one is back in the pool
+		return l.undo(ctx, p, left, err)
+	}
+
+	return nil
+}
+
+// withConn runs fn on a connection of its own with the configured timeouts
+func (l *Loader) withConn(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := l.setTimeouts(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if resetErr := l.resetTimeouts(context.WithoutCancel(ctx), conn); err == nil {
+			err = resetErr
+		}
+	}()
+
+	return fn(conn)
+}
+
+// loadSteps loads the tables step by step, each step with up to workers transactions that
+// are committed before the next step starts. The cleanup and the final updates get their
+// own transactions.
+//
+// Committed steps can't be rolled back: if a later one fails and Truncate is set, the tables
+// are cleaned again, so a failed load leaves them empty rather than half loaded. Without
+// Truncate the committed rows stay and the error says which tables they belong to.
+func (l *Loader) loadSteps(ctx context.Context, p *prepared, steps [][]string, workers int) error {
+	if l.Config.Truncate {
+		if err := l.inTx(ctx, func(tx *sql.Tx) error { return l.cleanup(ctx, tx, p) }); err != nil {
+			return err
+		}
+	}
+
+	var committed []string
+	for i, step := range steps {
+		done, err := l.loadLevel(ctx, p.fixtures, step, workers)
+		// A failed commit can follow commits of other transactions of the step
+		committed = append(committed, done...)
+		if err != nil {
+			if workers > 1 {
+				err = fmt.Errorf("level %d: %w", i+1, err)
+			}
+
+			return l.undo(ctx, p, committedRows(committed), err)
+		}
+	}
+
+	if err := l.inTx(ctx, func(tx *sql.Tx) error { return l.finish(ctx, tx, p) }); err != nil {
+		return l.undo(ctx, p, committedRows(committed), err)
+	}
+	if err := l.sequencesAfterCommit(ctx, p); err != nil {
+		return l.undo(ctx, p, committedRows(committed), err)
+	}
+
+	return nil
+}
+
+// committedRows describes the rows of the committed tables for undo
+func committedRows(tables []string) string {
+	if len(tables) == 0 {
+		return ""
+	}
+
+	return "the rows of " + strings.Join(tables, ", ")
+}
+
+// undo cleans the tables again after a failure that left committed rows, described by left
+// ("" if nothing was committed). The cleanup takes a connection of its own, so the load must
+// have released its connections before: with a small pool undo would wait for them forever.
+func (l *Loader) undo(ctx context.Context, p *prepared, left string, err error) error {
+	if left == "" {
+		return err
+	}
+
+	if !l.Config.Truncate {
+		return fmt.Errorf("%w (%s were committed and are left in place)", err, left)
+	}
+
+	log.Printf("[tx] load failed after %s were committed, cleaning the tables", left)
+	// Clean up even if the failure was a cancellation
+	ctx = context.WithoutCancel(ctx)
+	if cleanErr := l.inTx(ctx, func(tx *sql.Tx) error { return l.cleanup(ctx, tx, p) }); cleanErr != nil {
+		return fmt.Errorf("%w (cleaning the tables failed too, %s are left in place: %v)", err, left, cleanErr)
+	}
+
+	return err
+}
+
+// begin starts a transaction with the configured isolation level and timeouts
+func (l *Loader) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := l.DB.BeginTx(ctx, &sql.TxOptions{Isolation: l.Config.Tx.Isolation})
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+
+	if err := l.setTimeouts(ctx, tx); err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// end commits tx, or rolls it back and returns err if it is set. The timeouts are reset
+// first, MySQL keeps them on the pooled connection otherwise.
+func (l *Loader) end(ctx context.Context, tx *sql.Tx, err error) error {
+	if resetErr := l.resetTimeouts(context.WithoutCancel(ctx), tx); err == nil {
+		err = resetErr
+	}
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// inTx runs fn in a transaction of its own
+func (l *Loader) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := l.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	return l.end(ctx, tx, fn(tx))
+}
+
+func (l *Loader) setTimeouts(ctx context.Context, q db.Querier) error {
+	timeouts := l.Config.Tx.timeouts()
+	if timeouts.IsZero() {
+		return nil
+	}
+
+	if err := l.Database.SetTimeouts(ctx, q, timeouts, l.Config.DryRun); err != nil {
+		return fmt.Errorf("set timeouts: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Loader) resetTimeouts(ctx context.Context, q db.Querier) error {
+	timeouts := l.Config.Tx.timeouts()
+	if timeouts.IsZero() {
+		return nil
+	}
+
+	if err := l.Database.ResetTimeouts(ctx, q, timeouts, l.Config.DryRun); err != nil {
+		return fmt.Errorf("reset timeouts: %w", err)
+	}
+
+	return nil
+}
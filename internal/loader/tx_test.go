@@ -0,0 +1,213 @@
+package loader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+)
+
+func TestLoader_Load_TxPerTable(t *testing.T) {
+	mockDB := parallelMock()
+	recorder := &insertRecorder{MockDatabase: mockDB}
+	loader, dbMock := parallelLoader(t, recorder)
+	loader.Config.Workers = 0
+	loader.Config.Tx = TxConfig{Mode: TxPerTable}
+
+	// cleanup, one per table, sequences
+	for range 5 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+	}
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, mock.Anything, false).Return(nil, nil)
+
+	require.NoError(t, loader.Load(context.Background()))
+	require.Equal(t, []string{"public.products", "public.users", "public.users", "public.orders"}, recorder.inserted)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestLoader_Load_TxPerTableFailure(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders"})
+	loader.Config.Workers = 0
+	loader.Config.Truncate = false
+	loader.Config.Tx = TxConfig{Mode: TxPerTable}
+
+	for range 2 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+	}
+	dbMock.ExpectBegin()
+	dbMock.ExpectRollback()
+
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, `insert into "public.orders": boom `+
+		`(the rows of public.products, public.users were committed and are left in place)`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestLoader_Load_TxNone(t *testing.T) {
+	mockDB := parallelMock()
+	recorder := &insertRecorder{MockDatabase: mockDB}
+	loader, dbMock := parallelLoader(t, recorder)
+	loader.Config.Workers = 0
+	loader.Config.Tx = TxConfig{Mode: TxNone, LockTimeout: 5 * time.Second}
+
+	// No transaction at all, the timeouts are set on the connection and reset afterwards
+	timeouts := db.Timeouts{Lock: 5 * time.Second}
+	mockDB.On("SetTimeouts", mock.Anything, mock.AnythingOfType("*sql.Conn"), timeouts, false).Return(nil).Once()
+	mockDB.On("ResetTimeouts", mock.Anything, mock.AnythingOfType("*sql.Conn"), timeouts, false).Return(nil).Once()
+	mockDB.On("TruncateTables", mock.Anything, mock.AnythingOfType("*sql.Conn"), mock.Anything, false).Return(nil).Once()
+	mockDB.On("ResetSequences", mock.Anything, mock.AnythingOfType("*sql.Conn"), mock.Anything, false).Return(nil, nil).Once()
+
+	require.NoError(t, loader.Load(context.Background()))
+	require.Len(t, recorder.inserted, 4)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_Load_TxNoneFailureOneConnection(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB, fail: "public.orders"})
+	loader.Config.Workers = 0
+	loader.Config.Tx = TxConfig{Mode: TxNone}
+	loader.DB.SetMaxOpenConns(1)
+
+	// The cleanup after the failure gets the connection the load gave back
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	err := loadWithin(t, loader, 5*time.Second)
+	require.EqualError(t, err, `insert into "public.orders": boom`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertNumberOfCalls(t, "TruncateTables", 2)
+}
+
+// loadWithin runs Load and fails the test if it doesn't return in time
+func loadWithin(t *testing.T, loader *Loader, timeout time.Duration) error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- loader.Load(context.Background()) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("Load didn't return within %s", timeout)
+		return nil
+	}
+}
+
+func TestLoader_Load_TxTimeouts(t *testing.T) {
+	mockDB := parallelMock()
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB})
+	loader.Config.Workers = 0
+	loader.Config.Tx = TxConfig{Isolation: sql.LevelSerializable, StatementTimeout: time.Minute}
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	timeouts := db.Timeouts{Statement: time.Minute}
+	mockDB.On("SetTimeouts", mock.Anything, mock.AnythingOfType("*sql.Tx"), timeouts, false).Return(nil).Once()
+	mockDB.On("ResetTimeouts", mock.Anything, mock.AnythingOfType("*sql.Tx"), timeouts, false).Return(nil).Once()
+	mockDB.On("TruncateTables", mock.Anything, mock.Anything, mock.Anything, false).Return(nil).Once()
+	mockDB.On("ResetSequences", mock.Anything, mock.Anything, mock.Anything, false).Return(nil, nil).Once()
+
+	require.NoError(t, loader.Load(context.Background()))
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_Load_SequencesAfterCommit(t *testing.T) {
+	mockDB := parallelMock()
+	mockDB.implicitCommits = db.ImplicitCommits{Sequences: true}
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB})
+	loader.Config.Workers = 0
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectCommit()
+
+	// ALTER TABLE would commit the load transaction, so the reset runs once it is committed
+	mockDB.On("TruncateTables", mock.Anything, mock.AnythingOfType("*sql.Tx"), mock.Anything, false).Return(nil).Once()
+	mockDB.On("ResetSequences", mock.Anything, mock.AnythingOfType("*sql.Conn"), mock.Anything, false).
+		Run(func(mock.Arguments) { require.NoError(t, dbMock.ExpectationsWereMet()) }).
+		Return(nil, nil).Once()
+
+	require.NoError(t, loader.Load(context.Background()))
+	mockDB.AssertExpectations(t)
+}
+
+func TestLoader_Load_SequencesAfterCommitFailure(t *testing.T) {
+	mockDB := parallelMock()
+	mockDB.implicitCommits = db.ImplicitCommits{Sequences: true}
+	loader, dbMock := parallelLoader(t, &insertRecorder{MockDatabase: mockDB})
+	loader.Config.Workers = 0
+
+	// The load, then the cleanup of the committed rows
+	for range 2 {
+		dbMock.ExpectBegin()
+		dbMock.ExpectCommit()
+	}
+	mockDB.On("TruncateTables", mock.Anything, mock.AnythingOfType("*sql.Tx"), mock.Anything, false).Return(nil).Twice()
+	mockDB.On("ResetSequences", mock.Anything, mock.AnythingOfType("*sql.Conn"), mock.Anything, false).
+		Return(nil, errors.New("lock wait timeout")).Once()
+
+	err := loader.Load(context.Background())
+	require.EqualError(t, err, "reset sequences: lock wait timeout")
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestTxConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TxConfig
+		workers int
+		wantErr string
+	}{
+		{name: "default", config: TxConfig{}},
+		{name: "per-table", config: TxConfig{Mode: TxPerTable, Isolation: sql.LevelRepeatableRead}},
+		{name: "single with workers", config: TxConfig{Mode: TxSingle}, workers: 4},
+		{
+			name:    "unknown mode",
+			config:  TxConfig{Mode: "batch"},
+			wantErr: `unknown tx mode "batch" (supported modes: single, per-table, none)`,
+		},
+		{
+			name:    "isolation without transactions",
+			config:  TxConfig{Mode: TxNone, Isolation: sql.LevelSerializable},
+			wantErr: `tx mode "none" runs no transactions, an isolation level can't be set`,
+		},
+		{
+			name:    "per-table with workers",
+			config:  TxConfig{Mode: TxPerTable},
+			workers: 2,
+			wantErr: `tx mode "per-table" can't be combined with workers, which commit every level`,
+		},
+		{
+			name:    "negative timeout",
+			config:  TxConfig{LockTimeout: -time.Second},
+			wantErr: "timeouts must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validate(tt.workers)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
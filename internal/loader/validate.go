@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/parser"
+)
+
+// checkSchema validates the fixtures against the catalog before anything is written.
+// It reports unknown tables and columns, missing required columns and values for
+// generated columns (left only in strict mode), all problems at once.
+func checkSchema(fixtures parser.Fixtures, tables []string, catalog db.Catalog) error {
+	var errs []error
+	for _, table := range tables {
+		rows, ok := fixtures[table]
+		if !ok {
+			// A parent table pulled in by the dependency graph, nothing is inserted into it
+			continue
+		}
+
+		columns, ok := catalog[table]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown table", table))
+			continue
+		}
+
+		unknown := map[string]bool{}
+		generated := map[string]bool{}
+		for i, row := range rows {
+			for _, col := range sortedKeys(row) {
+				column, ok := columns[col]
+				switch {
+				case !ok:
+					unknown[col] = true
+				case column.Generated:
+					generated[col] = true
+				case row[col] == nil && !column.Nullable:
+					errs = append(errs, fmt.Errorf("%s %s: null value for NOT NULL column %q", table, rowLocation(i, row), col))
+				}
+			}
+
+			for _, col := range sortedKeys(columns) {
+				if _, ok := row[col]; !ok && columns[col].Required() {
+					errs = append(errs, fmt.Errorf("%s %s: missing required column %q", table, rowLocation(i, row), col))
+				}
+			}
+		}
+
+		for _, col := range sortedKeys(unknown) {
+			errs = append(errs, fmt.Errorf("%s: unknown column %q", table, col))
+		}
+		for _, col := range sortedKeys(generated) {
+			errs = append(errs, fmt.Errorf("%s: value for generated column %q", table, col))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("schema check failed (%d problems):\n%w", len(errs), errors.Join(errs...))
+}
+
+// dropGenerated removes values for generated columns, which the database computes itself.
+// Such values usually come from templates shared with other tables; each column is logged once.
+func dropGenerated(fixtures parser.Fixtures, catalog db.Catalog) {
+	for _, table := range sortedKeys(fixtures) {
+		dropped := map[string]bool{}
+		for _, row := range fixtures[table] {
+			for col := range row {
+				if column, ok := catalog.Column(table, col); ok && column.Generated {
+					delete(row, col)
+					dropped[col] = true
+				}
+			}
+		}
+
+		for _, col := range sortedKeys(dropped) {
+			log.Printf("[generated] skip values for column %s.%s", table, col)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,138 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rom8726/pgfixtures/internal/db"
+	"github.com/rom8726/pgfixtures/internal/parser"
+)
+
+func TestCheckSchema(t *testing.T) {
+	catalog := db.Catalog{
+		"public.users": {
+			"id":    {Name: "id", DataType: "integer", HasDefault: true},
+			"name":  {Name: "name", DataType: "text"},
+			"email": {Name: "email", DataType: "text", Nullable: true},
+			"slug":  {Name: "slug", DataType: "text", Nullable: true, Generated: true},
+		},
+		"public.orders": {
+			"id":      {Name: "id", DataType: "integer", HasDefault: true},
+			"user_id": {Name: "user_id", DataType: "integer"},
+		},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		fixtures := parser.Fixtures{
+			"public.users":  {{"id": 1, "name": "User1"}, {"name": "User2", "email": nil}},
+			"public.orders": {{"user_id": 1}},
+		}
+		require.NoError(t, checkSchema(fixtures, []string{"public.orders", "public.users"}, catalog))
+	})
+
+	t.Run("parents without rows are skipped", func(t *testing.T) {
+		fixtures := parser.Fixtures{"public.orders": {{"user_id": 1}}}
+		require.NoError(t, checkSchema(fixtures, []string{"public.orders", "public.missing_parent"}, catalog))
+	})
+
+	t.Run("all problems reported", func(t *testing.T) {
+		fixtures := parser.Fixtures{
+			"public.users": {
+				{"id": 1, "nmae": "typo", "slug": "x"},
+				{"id": 2, "name": nil, "nmae": "typo"},
+			},
+			"public.orders":   {{"id": 1}},
+			"public.invoices": {{"id": 1}},
+		}
+		err := checkSchema(fixtures, []string{"public.invoices", "public.orders", "public.users"}, catalog)
+		require.Error(t, err)
+		require.Equal(t, `schema check failed (6 problems):
+public.invoices: unknown table
+public.orders row #1 (id=1): missing required column "user_id"
+public.users row #1 (id=1): missing required column "name"
+public.users row #2 (id=2): null value for NOT NULL column "name"
+public.users: unknown column "nmae"
+public.users: value for generated column "slug"`, err.Error())
+	})
+}
+
+func TestLoader_Load_CheckSchemaBeforeTruncate(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "fixtures.yml")
+	require.NoError(t, os.WriteFile(fixturePath, []byte("public.users:\n  - id: 1\n"), 0644))
+
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	mockDB := &MockDatabase{}
+	mockDB.On("ResolveTables", mock.Anything, mock.Anything, []string{"public.users"}).
+		Return(map[string]string{"public.users": "public.users"}, nil)
+	mockDB.On("GetDependencyGraph", mock.Anything, mock.Anything).Return(map[string][]string{}, nil)
+	mockDB.On("GetColumns", mock.Anything, mock.Anything, []string{"public.users"}).Return(db.Catalog{}, nil)
+
+	loader := &Loader{
+		DB:       sqlDB,
+		Database: mockDB,
+		Config: LoaderConfig{
+			FilePath:    fixturePath,
+			Truncate:    true,
+			CheckSchema: true,
+		},
+	}
+
+	err = loader.Load(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "public.users: unknown table")
+
+	// No transaction was started, nothing was truncated
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	mockDB.AssertExpectations(t)
+}
+
+func TestDropGenerated(t *testing.T) {
+	catalog := db.Catalog{
+		"public.users": {
+			"id":   {Name: "id", DataType: "integer", HasDefault: true},
+			"slug": {Name: "slug", DataType: "text", Generated: true},
+		},
+	}
+	fixtures := parser.Fixtures{
+		"public.users":  {{"id": 1, "slug": "a"}, {"id": 2}},
+		"public.events": {{"slug": "kept, unknown table"}},
+	}
+
+	dropGenerated(fixtures, catalog)
+	require.Equal(t, parser.Fixtures{
+		"public.users":  {{"id": 1}, {"id": 2}},
+		"public.events": {{"slug": "kept, unknown table"}},
+	}, fixtures)
+}
+
+func TestLoader_InsertRow_StrictGenerated(t *testing.T) {
+	d, m, err := sqlmock.New()
+	require.NoError(t, err)
+	defer d.Close()
+
+	m.ExpectBegin()
+	tx, err := d.Begin()
+	require.NoError(t, err)
+
+	loader := &Loader{
+		DB:       d,
+		Database: &MockDatabase{},
+		Config:   LoaderConfig{StrictGenerated: true},
+		columns: db.Catalog{
+			"public.users": {"slug": {Name: "slug", DataType: "text", Generated: true}},
+		},
+	}
+
+	err = loader.insertRow(context.Background(), tx, "public.users", 0, map[string]any{"id": 1, "slug": "a"})
+	require.EqualError(t, err, `row #1 (id=1), column "slug": value for generated column`)
+}
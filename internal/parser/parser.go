@@ -4,15 +4,27 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
+	"sort"
 
 	"gopkg.in/yaml.v3"
-)
 
-var evalRe = regexp.MustCompile(`^\$eval\((.+)\)$`)
+	"github.com/rom8726/pgfixtures/internal/db"
+)
 
 type Fixtures map[string][]map[string]any
 
+// Document is a parsed fixture file together with its includes
+type Document struct {
+	Fixtures Fixtures
+	// Sequences maps a sequence (public.users_id_seq) or a serial column (public.users.id)
+	// to the value it should produce next
+	Sequences map[string]int64
+	// Dependencies maps a table to the tables it depends on without a foreign key
+	// (trigger lookups, constraints enforced by the application)
+	Dependencies map[string][]string
+}
+
 type TemplateDef struct {
 	Table   string         `yaml:"table"`
 	Name    string         `yaml:"name"`
@@ -20,45 +32,162 @@ type TemplateDef struct {
 	Fields  map[string]any `yaml:"fields"`
 }
 
+// UnmarshalYAML implements yaml.Unmarshaler, decoding fields with exact numbers
+func (t *TemplateDef) UnmarshalYAML(node *yaml.Node) error {
+	var raw struct {
+		Table   string    `yaml:"table"`
+		Name    string    `yaml:"name"`
+		Extends string    `yaml:"extends,omitempty"`
+		Fields  yaml.Node `yaml:"fields"`
+	}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	fields, err := nodeValue(&raw.Fields)
+	if err != nil {
+		return err
+	}
+
+	t.Table, t.Name, t.Extends = raw.Table, raw.Name, raw.Extends
+	t.Fields = nil
+	if fields != nil {
+		m, ok := fields.(map[string]any)
+		if !ok {
+			return fmt.Errorf("fields of template %s must be a map", raw.Name)
+		}
+		t.Fields = m
+	}
+
+	return nil
+}
+
 type AllTemplates map[string]map[string]TemplateDef
 
 type rawFixtureFile struct {
-	Include   any            `yaml:"include"`
-	Templates []TemplateDef  `yaml:"templates"`
-	Fixtures  map[string]any `yaml:",inline"`
+	Include any `yaml:"include"`
+	// DefaultSchema qualifies the unqualified table names of this file
+	DefaultSchema string               `yaml:"default_schema"`
+	Templates     []TemplateDef        `yaml:"templates"`
+	Sequences     map[string]int64     `yaml:"sequences"`
+	Dependencies  map[string][]string  `yaml:"dependencies"`
+	Fixtures      map[string]yaml.Node `yaml:",inline"`
+}
+
+// Canonicalize renames tables using names (fixture key -> canonical name). Rows of keys
+// that resolve to the same table are merged by id, like rows of included files.
+func (f Fixtures) Canonicalize(names map[string]string) Fixtures {
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make(Fixtures, len(f))
+	for _, key := range keys {
+		name, ok := names[key]
+		if !ok {
+			name = key
+		}
+		result[name] = mergeRowsByID(result[name], f[key])
+	}
+
+	return result
+}
+
+// qualifyTable prefixes an unqualified table name with schema (if set)
+func qualifyTable(table, schema string) string {
+	if schema == "" || len(db.SplitQualified(table)) > 1 {
+		return table
+	}
+
+	return schema + "." + table
+}
+
+// qualifySequence prefixes a key of the sequences section with schema (if set) when it is a bare
+// sequence name or table.column with an unqualified table of the file. Other two-part keys are
+// schema.sequence and stay as they are.
+func qualifySequence(name, schema string, tables map[string]bool) string {
+	parts := db.SplitQualified(name)
+	if schema == "" || len(parts) > 2 || (len(parts) == 2 && !tables[parts[0]]) {
+		return name
+	}
+
+	return schema + "." + name
 }
 
+// unqualifiedTables returns the table names a file uses without a schema, for qualifySequence
+func unqualifiedTables(raw *rawFixtureFile) map[string]bool {
+	tables := map[string]bool{}
+	add := func(name string) {
+		if parts := db.SplitQualified(name); len(parts) == 1 {
+			tables[parts[0]] = true
+		}
+	}
+
+	for key := range raw.Fixtures {
+		add(key)
+	}
+	for _, tmpl := range raw.Templates {
+		add(tmpl.Table)
+	}
+	for table, parents := range raw.Dependencies {
+		add(table)
+		for _, parent := range parents {
+			add(parent)
+		}
+	}
+
+	return tables
+}
+
+// mergeRowsByID merges rows by their id: a later row replaces an earlier one with the same id
+// in place, so the resulting order (rows with id first, by first appearance) is stable.
 func mergeRowsByID(slices ...[]map[string]any) []map[string]any {
-	byID := map[any]map[string]any{}
+	posByID := map[any]int{}
+	var result []map[string]any
 	var noIDRows []map[string]any
 	for _, rows := range slices {
 		for _, row := range rows {
 			id, hasID := row["id"]
-			if hasID {
-				byID[id] = row
-			} else {
+			if !hasID {
 				noIDRows = append(noIDRows, row)
+				continue
+			}
+			if pos, ok := posByID[id]; ok {
+				result[pos] = row
+			} else {
+				posByID[id] = len(result)
+				result = append(result, row)
 			}
 		}
 	}
-	var result []map[string]any
-	for _, row := range byID {
-		result = append(result, row)
-	}
 	result = append(result, noIDRows...)
 	return result
 }
 
 func ParseFileWithInclude(path string, visited map[string]bool) (Fixtures, error) {
-	fixtures, _, err := parseFileWithTemplatesV2(path, visited)
-	return fixtures, err
+	doc, _, err := parseFileWithTemplatesV2(path, visited)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Fixtures, nil
 }
 
 func ParseFile(path string) (Fixtures, error) {
 	return ParseFileWithInclude(path, map[string]bool{})
 }
 
-func parseFileWithTemplatesV2(path string, visited map[string]bool) (Fixtures, AllTemplates, error) {
+// ParseDocument parses a fixture file with its includes, including the sections
+// that are not table data. Sequence values of the including file win, dependencies
+// of all files are combined.
+func ParseDocument(path string) (*Document, error) {
+	doc, _, err := parseFileWithTemplatesV2(path, map[string]bool{})
+	return doc, err
+}
+
+func parseFileWithTemplatesV2(path string, visited map[string]bool) (*Document, AllTemplates, error) {
 	absPath, _ := filepath.Abs(path)
 	if visited[absPath] {
 		return nil, nil, fmt.Errorf("cyclic include detected: %s", absPath)
@@ -76,6 +205,8 @@ func parseFileWithTemplatesV2(path string, visited map[string]bool) (Fixtures, A
 	}
 
 	result := Fixtures{}
+	sequences := map[string]int64{}
+	dependencies := map[string][]string{}
 	allTemplates := AllTemplates{}
 
 	// 1. Process include
@@ -96,13 +227,19 @@ func parseFileWithTemplatesV2(path string, visited map[string]bool) (Fixtures, A
 			if !filepath.IsAbs(incPath) {
 				incAbs = filepath.Join(filepath.Dir(absPath), incPath)
 			}
-			incFixtures, incTemplates, err := parseFileWithTemplatesV2(incAbs, visited)
+			incDoc, incTemplates, err := parseFileWithTemplatesV2(incAbs, visited)
 			if err != nil {
 				return nil, nil, err
 			}
-			for table, rows := range incFixtures {
+			for table, rows := range incDoc.Fixtures {
 				result[table] = mergeRowsByID(result[table], rows)
 			}
+			for name, value := range incDoc.Sequences {
+				sequences[name] = value
+			}
+			for table, parents := range incDoc.Dependencies {
+				dependencies[table] = appendUnique(dependencies[table], parents...)
+			}
 			for table, tmap := range incTemplates {
 				if allTemplates[table] == nil {
 					allTemplates[table] = map[string]TemplateDef{}
@@ -115,11 +252,16 @@ func parseFileWithTemplatesV2(path string, visited map[string]bool) (Fixtures, A
 	}
 
 	// 2. Collect templates from the current file
+	baseDir := filepath.Dir(absPath)
 	for _, tmpl := range raw.Templates {
+		tmpl.Table = qualifyTable(tmpl.Table, raw.DefaultSchema)
 		table := tmpl.Table
 		if tmpl.Fields == nil {
 			tmpl.Fields = map[string]any{}
 		}
+		if _, err := resolveValues(tmpl.Fields, baseDir); err != nil {
+			return nil, nil, fmt.Errorf("template %s of %s: %w", tmpl.Name, table, err)
+		}
 		if allTemplates[table] == nil {
 			allTemplates[table] = map[string]TemplateDef{}
 		}
@@ -127,10 +269,15 @@ func parseFileWithTemplatesV2(path string, visited map[string]bool) (Fixtures, A
 	}
 
 	// 3. Collect regular tables
-	for key, val := range raw.Fixtures {
-		if key == "templates" {
+	for key, node := range raw.Fixtures {
+		if key == "templates" || key == "sequences" || key == "dependencies" {
 			continue
 		}
+		key = qualifyTable(key, raw.DefaultSchema)
+		val, err := nodeValue(&node)
+		if err != nil {
+			return nil, nil, fmt.Errorf("table %s: %w", key, err)
+		}
 		arr, ok := val.([]any)
 		if !ok {
 			return nil, nil, fmt.Errorf("table %s must be an array", key)
@@ -141,16 +288,51 @@ func parseFileWithTemplatesV2(path string, visited map[string]bool) (Fixtures, A
 			if !ok {
 				return nil, nil, fmt.Errorf("row in %s must be a map", key)
 			}
-			if _, hasExt := row["extends"]; hasExt {
+			if _, err := resolveValues(row, baseDir); err != nil {
+				return nil, nil, fmt.Errorf("table %s: %w", key, err)
+			}
+			if _, hasRepeat := row[repeatKey]; hasRepeat {
+				repeated, err := expandRepeat(row, key, allTemplates)
+				if err != nil {
+					return nil, nil, err
+				}
+				rows = append(rows, repeated...)
+			} else if _, hasExt := row["extends"]; hasExt {
 				rows = append(rows, resolveExtendsV2(row, key, allTemplates))
 			} else {
 				rows = append(rows, deepCopyMap(row))
 			}
 		}
+		for i, row := range rows {
+			rows[i] = unwrapResolved(row).(map[string]any)
+		}
 		result[key] = mergeRowsByID(result[key], rows)
 	}
 
-	return result, allTemplates, nil
+	tables := unqualifiedTables(&raw)
+	for name, value := range raw.Sequences {
+		sequences[qualifySequence(name, raw.DefaultSchema, tables)] = value
+	}
+
+	for table, parents := range raw.Dependencies {
+		table = qualifyTable(table, raw.DefaultSchema)
+		for _, parent := range parents {
+			dependencies[table] = appendUnique(dependencies[table], qualifyTable(parent, raw.DefaultSchema))
+		}
+	}
+
+	return &Document{Fixtures: result, Sequences: sequences, Dependencies: dependencies}, allTemplates, nil
+}
+
+// appendUnique appends the values that are not in list yet
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+
+	return list
 }
 
 func resolveTemplateFields(table, name string, allTemplates AllTemplates, visited map[string]bool) map[string]any {
@@ -201,17 +383,3 @@ func deepCopyMap(src map[string]any) map[string]any {
 	}
 	return dst
 }
-
-func IsEval(val any) (string, bool) {
-	s, ok := val.(string)
-	if !ok {
-		return "", false
-	}
-
-	m := evalRe.FindStringSubmatch(s)
-	if len(m) != 2 {
-		return "", false
-	}
-
-	return m[1], true
-}
@@ -335,50 +335,133 @@ public.users:
 	require.Contains(t, users[0], "created_at")
 }
 
-func TestIsEval(t *testing.T) {
-	tests := []struct {
-		name  string
-		input any
-		expr  string
-		ok    bool
-	}{
-		{
-			name:  "valid eval",
-			input: "$eval(SELECT 1)",
-			expr:  "SELECT 1",
-			ok:    true,
-		},
-		{
-			name:  "not a string",
-			input: 123,
-			expr:  "",
-			ok:    false,
-		},
-		{
-			name:  "no eval prefix",
-			input: "SELECT 1",
-			expr:  "",
-			ok:    false,
-		},
-		{
-			name:  "malformed eval",
-			input: "$evalSELECT 1)",
-			expr:  "",
-			ok:    false,
-		},
-		{
-			name:  "empty eval",
-			input: "$eval()",
-			expr:  "",
-			ok:    false,
-		},
-	}
+func TestMergeRowsByID_StableOrder(t *testing.T) {
+	merged := mergeRowsByID(
+		[]map[string]any{{"id": 3}, {"id": 1}, {"name": "no id"}},
+		[]map[string]any{{"id": 1, "name": "override"}, {"id": 2}},
+	)
+	require.Equal(t, []map[string]any{
+		{"id": 3},
+		{"id": 1, "name": "override"},
+		{"id": 2},
+		{"name": "no id"},
+	}, merged)
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			expr, ok := IsEval(tt.input)
-			require.Equal(t, tt.ok, ok)
-			require.Equal(t, tt.expr, expr)
-		})
+func TestParseFile_DefaultSchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fixtures.yml")
+	data := `
+default_schema: app
+templates:
+  - table: users
+    name: base
+    fields:
+      active: true
+users:
+  - id: 1
+    extends: base
+public.logs:
+  - id: 1
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+
+	fixtures, err := ParseFile(path)
+	require.NoError(t, err)
+	require.Equal(t, Fixtures{
+		"app.users":   {{"id": 1, "active": true}},
+		"public.logs": {{"id": 1}},
+	}, fixtures)
+}
+
+func TestFixtures_Canonicalize(t *testing.T) {
+	fixtures := Fixtures{
+		"users":            {{"id": 1, "name": "a"}},
+		"public.users":     {{"id": 1, "name": "b"}, {"id": 2}},
+		"public.posts":     {{"id": 1}},
+		`"public"."users"`: {{"id": 3}},
 	}
+
+	result := fixtures.Canonicalize(map[string]string{
+		"users":            "public.users",
+		"public.users":     "public.users",
+		`"public"."users"`: "public.users",
+	})
+	// Keys are merged in sorted order, so the unqualified key wins
+	require.Equal(t, Fixtures{
+		"public.posts": {{"id": 1}},
+		"public.users": {{"id": 3}, {"id": 1, "name": "a"}, {"id": 2}},
+	}, result)
+}
+
+func TestParseDocument_Sequences(t *testing.T) {
+	d := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(d, "base.yml"), []byte(`
+sequences:
+  public.users_id_seq: 100
+  public.orders.id: 500
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(d, "main.yml"), []byte(`
+include: base.yml
+sequences:
+  public.users_id_seq: 1000
+public.users:
+  - id: 1
+`), 0644))
+
+	doc, err := ParseDocument(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, Fixtures{"public.users": {{"id": 1}}}, doc.Fixtures)
+	require.Equal(t, map[string]int64{
+		"public.users_id_seq": 1000,
+		"public.orders.id":    500,
+	}, doc.Sequences)
+}
+
+func TestParseDocument_SequencesDefaultSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+default_schema: app
+sequences:
+  users_id_seq: 100
+  users.id: 200
+  public.orders_id_seq: 300
+  public.orders.id: 400
+users:
+  - id: 1
+`), 0644))
+
+	doc, err := ParseDocument(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{
+		"app.users_id_seq":     100,
+		"app.users.id":         200,
+		"public.orders_id_seq": 300,
+		"public.orders.id":     400,
+	}, doc.Sequences)
+}
+
+func TestParseDocument_Dependencies(t *testing.T) {
+	d := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(d, "base.yml"), []byte(`
+dependencies:
+  public.audit_log: [public.users]
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(d, "main.yml"), []byte(`
+include: base.yml
+default_schema: app
+dependencies:
+  public.audit_log: [public.users, settings]
+  events: [public.users]
+events:
+  - id: 1
+`), 0644))
+
+	doc, err := ParseDocument(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, Fixtures{"app.events": {{"id": 1}}}, doc.Fixtures)
+	require.Equal(t, map[string][]string{
+		"public.audit_log": {"public.users", "app.settings"},
+		"app.events":       {"public.users"},
+	}, doc.Dependencies)
 }
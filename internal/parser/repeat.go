@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// repeatKey marks a row that is expanded into N rows: `- $repeat: 500`
+const repeatKey = "$repeat"
+
+// Functions available inside {{ ... }} of repeated rows.
+// Arguments are piped last, so `{{.i | add 100}}` means i + 100.
+var repeatFuncs = template.FuncMap{
+	"add": func(n, i int) int { return i + n },
+	"sub": func(n, i int) int { return i - n },
+	"mul": func(n, i int) int { return i * n },
+	"div": func(n, i int) int { return i / n },
+	"mod": func(n, i int) int { return i % n },
+}
+
+// expandRepeat turns a `$repeat` row into regular rows. Every string field may use
+// text/template syntax with .i (1-based row number) and .n (total number of rows).
+func expandRepeat(row map[string]any, table string, allTemplates AllTemplates) ([]map[string]any, error) {
+	count, ok := row[repeatKey].(int)
+	if !ok || count < 0 {
+		return nil, fmt.Errorf("table %s: %s must be a non-negative integer, got %v", table, repeatKey, row[repeatKey])
+	}
+
+	base := deepCopyMap(row)
+	delete(base, repeatKey)
+	if _, hasExt := base["extends"]; hasExt {
+		base = resolveExtendsV2(base, table, allTemplates)
+	}
+
+	rows := make([]map[string]any, 0, count)
+	for i := 1; i <= count; i++ {
+		data := map[string]int{"i": i, "n": count}
+		expanded := make(map[string]any, len(base))
+		for col, val := range base {
+			v, err := renderRepeatValue(val, data)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %s field %q: %w", table, repeatKey, col, err)
+			}
+			expanded[col] = v
+		}
+		rows = append(rows, expanded)
+	}
+
+	return rows, nil
+}
+
+func renderRepeatValue(val any, data map[string]int) (any, error) {
+	switch v := val.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+
+		tmpl, err := template.New("").Funcs(repeatFuncs).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return nil, err
+		}
+
+		return retypeScalar(tmpl, sb.String()), nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			rendered, err := renderRepeatValue(item, data)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			rendered, err := renderRepeatValue(item, data)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+
+		return out, nil
+	default:
+		return val, nil
+	}
+}
+
+// retypeScalar makes the output of a field that is a single action, such as `id: "{{.i}}"`,
+// an integer just like `id: 1`. Anything else stays a string, so text around an action,
+// values such as "2024-01-01" or "0x1F" and formatted numbers such as "007" keep their type.
+func retypeScalar(tmpl *template.Template, s string) any {
+	nodes := tmpl.Tree.Root.Nodes
+	if len(nodes) != 1 || nodes[0].Type() != parse.NodeAction {
+		return s
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || strconv.Itoa(n) != s {
+		return s
+	}
+
+	return n
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFile_Repeat(t *testing.T) {
+	d := t.TempDir()
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`templates:
+  - table: public.users
+    name: base_user
+    fields:
+      name: "User {{.i}} of {{.n}}"
+      is_admin: false
+public.users:
+  - id: 1
+    name: Admin
+  - $repeat: 3
+    extends: base_user
+    id: "{{.i | add 100}}"
+    email: "user{{.i}}@example.com"
+    created_at: $now(-24h)
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{
+		{"id": 1, "name": "Admin"},
+		{"id": 101, "name": "User 1 of 3", "is_admin": false, "email": "user1@example.com", "created_at": "$now(-24h)"},
+		{"id": 102, "name": "User 2 of 3", "is_admin": false, "email": "user2@example.com", "created_at": "$now(-24h)"},
+		{"id": 103, "name": "User 3 of 3", "is_admin": false, "email": "user3@example.com", "created_at": "$now(-24h)"},
+	}, fixtures["public.users"])
+}
+
+func TestParseFile_RepeatMergedByID(t *testing.T) {
+	d := t.TempDir()
+	_ = os.WriteFile(filepath.Join(d, "base.yml"), []byte(`public.users:
+  - $repeat: 3
+    id: "{{.i}}"
+    name: "user{{.i}}"
+`), 0644)
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`include: base.yml
+public.users:
+  - id: 2
+    name: Overridden
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{
+		{"id": 1, "name": "user1"},
+		{"id": 2, "name": "Overridden"},
+		{"id": 3, "name": "user3"},
+	}, fixtures["public.users"])
+}
+
+func TestParseFile_RepeatKeepsStrings(t *testing.T) {
+	d := t.TempDir()
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`public.events:
+  - $repeat: 1
+    id: "{{.i}}"
+    day: "2024-01-{{.i | add 10}}"
+    code: "0x{{.i | add 30}}"
+    flag: "{{if eq .i 1}}true{{end}}"
+    number: "{{printf \"%03d\" .i}}"
+    signed: "{{printf \"%+d\" .i}}"
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{
+		{"id": 1, "day": "2024-01-11", "code": "0x31", "flag": "true", "number": "001", "signed": "+1"},
+	}, fixtures["public.events"])
+}
+
+func TestParseFile_RepeatErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		errText  string
+	}{
+		{
+			name: "non-integer count",
+			contents: `public.users:
+  - $repeat: many
+    name: x
+`,
+			errText: "$repeat must be a non-negative integer",
+		},
+		{
+			name: "invalid template",
+			contents: `public.users:
+  - $repeat: 2
+    name: "{{.i"
+`,
+			errText: `field "name"`,
+		},
+		{
+			name: "unknown variable",
+			contents: `public.users:
+  - $repeat: 2
+    name: "{{.j}}"
+`,
+			errText: `field "name"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := t.TempDir()
+			_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(tt.contents), 0644)
+			_, err := ParseFile(filepath.Join(d, "main.yml"))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.errText)
+		})
+	}
+}
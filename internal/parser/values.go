@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rom8726/pgfixtures/internal/directive"
+)
+
+// staticDirectives are resolved by the parser, so they never reach the loader
+var staticDirectives = map[string]bool{
+	"file":   true,
+	"base64": true,
+	"hex":    true,
+	"json":   true,
+}
+
+// IsStaticDirective reports whether name is a directive resolved while parsing
+func IsStaticDirective(name string) bool {
+	return staticDirectives[name]
+}
+
+// resolveValues resolves the directives that only depend on the fixture file itself:
+//
+//	$file(path)    -> []byte with the file contents, path is relative to the declaring file
+//	$base64(data)  -> []byte
+//	$hex(data)     -> []byte
+//	$json(doc)     -> compact JSON text
+//
+// Maps and lists are processed recursively. The results are wrapped in resolved until
+// unwrapResolved, so `$repeat` doesn't render file or JSON contents as templates.
+func resolveValues(val any, baseDir string) (any, error) {
+	switch v := val.(type) {
+	case string:
+		name, arg, ok := directive.Parse(v)
+		if !ok {
+			return v, nil
+		}
+
+		switch name {
+		case "file":
+			path := strings.TrimSpace(arg)
+			if path == "" {
+				return nil, fmt.Errorf("$file: path is required")
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("$file(%s): %w", arg, err)
+			}
+
+			return resolved{data}, nil
+		case "base64":
+			data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(arg))
+			if err != nil {
+				return nil, fmt.Errorf("$base64: %w", err)
+			}
+
+			return resolved{data}, nil
+		case "hex":
+			s := strings.TrimSpace(arg)
+			s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), `\x`)
+			data, err := hex.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("$hex: %w", err)
+			}
+
+			return resolved{data}, nil
+		case "json":
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, []byte(arg)); err != nil {
+				return nil, fmt.Errorf("$json: invalid JSON: %w", err)
+			}
+
+			return resolved{buf.String()}, nil
+		default:
+			return v, nil
+		}
+	case map[string]any:
+		for k, item := range v {
+			resolved, err := resolveValues(item, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = resolved
+		}
+
+		return v, nil
+	case []any:
+		for i, item := range v {
+			resolved, err := resolveValues(item, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+
+		return v, nil
+	default:
+		return val, nil
+	}
+}
+
+// resolved holds the value of a static directive while rows are expanded
+type resolved struct {
+	value any
+}
+
+// unwrapResolved returns a copy of val without resolved wrappers. Maps and lists are copied,
+// they may be shared with templates.
+func unwrapResolved(val any) any {
+	switch v := val.(type) {
+	case resolved:
+		return v.value
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = unwrapResolved(item)
+		}
+
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = unwrapResolved(item)
+		}
+
+		return out
+	default:
+		return val
+	}
+}
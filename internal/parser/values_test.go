@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFile_TypedValues(t *testing.T) {
+	d := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(d, "blobs"), 0755))
+	_ = os.WriteFile(filepath.Join(d, "blobs", "avatar.png"), []byte{0x89, 'P', 'N', 'G'}, 0644)
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`public.files:
+  - id: 1
+    content: $file(blobs/avatar.png)
+    raw: $base64(aGVsbG8=)
+    digest: $hex(0xdeadbeef)
+    meta: '$json({"tags": ["a", "b"], "size": 4})'
+    nested:
+      key: $hex(0102)
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{
+		"id":      1,
+		"content": []byte{0x89, 'P', 'N', 'G'},
+		"raw":     []byte("hello"),
+		"digest":  []byte{0xde, 0xad, 0xbe, 0xef},
+		"meta":    `{"tags":["a","b"],"size":4}`,
+		"nested":  map[string]any{"key": []byte{0x01, 0x02}},
+	}}, fixtures["public.files"])
+}
+
+func TestParseFile_TypedValuesNotRendered(t *testing.T) {
+	d := t.TempDir()
+	_ = os.WriteFile(filepath.Join(d, "page.html"), []byte("<p>{{ .title }}</p>"), 0644)
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`templates:
+  - table: public.pages
+    name: page
+    fields:
+      settings: '$json({"greeting": "{{name}}"})'
+public.pages:
+  - $repeat: 2
+    extends: page
+    id: "{{.i}}"
+    body: $file(page.html)
+    meta: '$json({"pattern": "{{.i}}"})'
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	for i, row := range fixtures["public.pages"] {
+		require.Equal(t, map[string]any{
+			"id":       i + 1,
+			"body":     []byte("<p>{{ .title }}</p>"),
+			"meta":     `{"pattern":"{{.i}}"}`,
+			"settings": `{"greeting":"{{name}}"}`,
+		}, row)
+	}
+}
+
+func TestParseFile_TypedValues_RelativeToIncludedFile(t *testing.T) {
+	d := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(d, "shared", "data"), 0755))
+	_ = os.WriteFile(filepath.Join(d, "shared", "data", "doc.txt"), []byte("shared doc"), 0644)
+	_ = os.WriteFile(filepath.Join(d, "shared", "base.yml"), []byte(`templates:
+  - table: public.docs
+    name: base
+    fields:
+      body: $file(data/doc.txt)
+public.docs:
+  - id: 1
+    body: $file(data/doc.txt)
+`), 0644)
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`include: shared/base.yml
+public.docs:
+  - id: 2
+    extends: base
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{
+		{"id": 1, "body": []byte("shared doc")},
+		{"id": 2, "body": []byte("shared doc")},
+	}, fixtures["public.docs"])
+}
+
+func TestParseFile_TypedValuesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		errText string
+	}{
+		{name: "missing file", value: "$file(nope.bin)", errText: "$file(nope.bin)"},
+		{name: "bad base64", value: "$base64(!!!)", errText: "$base64"},
+		{name: "bad hex", value: "$hex(xyz)", errText: "$hex"},
+		{name: "bad json", value: "'$json({oops)'", errText: "invalid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := t.TempDir()
+			_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte("public.t:\n  - v: "+tt.value+"\n"), 0644)
+			_, err := ParseFile(filepath.Join(d, "main.yml"))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.errText)
+		})
+	}
+}
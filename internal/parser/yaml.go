@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+var jsonNumberRe = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+// Number is a YAML number kept exactly as written. Decimals and integers that don't fit
+// into int are decoded as Number, so NUMERIC values and big ids don't lose precision.
+type Number string
+
+// String returns the number as written in the fixture
+func (n Number) String() string {
+	return string(n)
+}
+
+// Float64 returns the number as float64 (possibly losing precision)
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Value implements driver.Valuer: the number is sent as text and cast by the database
+func (n Number) Value() (driver.Value, error) {
+	return string(n), nil
+}
+
+// MarshalJSON implements json.Marshaler without quoting the number
+func (n Number) MarshalJSON() ([]byte, error) {
+	if jsonNumberRe.MatchString(string(n)) {
+		return []byte(n), nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", string(n))
+	}
+
+	return []byte(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+// nodeValue converts a YAML node into a Go value like yaml.Unmarshal into `any` does,
+// except that floats and big integers are kept as Number.
+func nodeValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case 0:
+		return nil, nil
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+
+		return nodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+	case yaml.SequenceNode:
+		items := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			v, err := nodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+
+		return items, nil
+	case yaml.MappingNode:
+		return mappingValue(node)
+	case yaml.ScalarNode:
+		return scalarValue(node)
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+}
+
+func mappingValue(node *yaml.Node) (map[string]any, error) {
+	out := make(map[string]any, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valNode := node.Content[i], node.Content[i+1]
+
+		// Merge keys (<<: *base) contribute fields that are not set explicitly
+		if keyNode.Tag == "!!merge" {
+			if err := mergeInto(out, valNode); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		v, err := nodeValue(valNode)
+		if err != nil {
+			return nil, err
+		}
+		out[keyNode.Value] = v
+	}
+
+	return out, nil
+}
+
+func mergeInto(out map[string]any, node *yaml.Node) error {
+	v, err := nodeValue(node)
+	if err != nil {
+		return err
+	}
+
+	var sources []any
+	if list, ok := v.([]any); ok {
+		sources = list
+	} else {
+		sources = []any{v}
+	}
+
+	for _, src := range sources {
+		m, ok := src.(map[string]any)
+		if !ok {
+			return fmt.Errorf("line %d: merge value must be a map", node.Line)
+		}
+		for k, item := range m {
+			if _, exists := out[k]; !exists {
+				out[k] = item
+			}
+		}
+	}
+
+	return nil
+}
+
+func scalarValue(node *yaml.Node) (any, error) {
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	switch node.ShortTag() {
+	case "!!float":
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return v, nil
+		}
+
+		return Number(node.Value), nil
+	case "!!int":
+		if _, ok := v.(int); !ok {
+			return Number(node.Value), nil
+		}
+	}
+
+	return v, nil
+}
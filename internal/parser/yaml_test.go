@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFile_ExactNumbers(t *testing.T) {
+	d := t.TempDir()
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`public.products:
+  - id: 1
+    price: 399.99
+    weight: 2.50
+    big: 123456789012345678901234
+    count: 3
+    created_at: 2024-01-01
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{
+		"id":         1,
+		"price":      Number("399.99"),
+		"weight":     Number("2.50"),
+		"big":        Number("123456789012345678901234"),
+		"count":      3,
+		"created_at": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}, fixtures["public.products"])
+}
+
+func TestParseFile_MergeKeys(t *testing.T) {
+	d := t.TempDir()
+	_ = os.WriteFile(filepath.Join(d, "main.yml"), []byte(`templates:
+  - table: public.products
+    name: base
+    fields: &defaults
+      price: 1.50
+      active: true
+public.products:
+  - id: 1
+    <<: *defaults
+    active: false
+`), 0644)
+
+	fixtures, err := ParseFile(filepath.Join(d, "main.yml"))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{
+		{"id": 1, "price": Number("1.50"), "active": false},
+	}, fixtures["public.products"])
+}
+
+func TestNumber_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]any{"a": Number("2.50"), "b": Number("+1.5"), "c": Number(".5")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": 2.50, "b": 1.5, "c": 0.5}`, string(data))
+	require.Equal(t, `{"a":2.50,"b":1.5,"c":0.5}`, string(data))
+}
@@ -44,15 +44,27 @@ func Load(ctx context.Context, config *Config) error {
 	if err != nil {
 		return fmt.Errorf("create database implementation: %w", err)
 	}
+	if mysqlDB, ok := dbImpl.(*db.MySQLDatabase); ok {
+		mysqlDB.SchemaAliases = config.SchemaAliases
+		mysqlDB.UseTruncate = config.FastTruncate
+	}
 
 	l := loader.Loader{
 		DB:       database,
 		Database: dbImpl,
 		Config: loader.LoaderConfig{
-			FilePath: config.FilePath,
-			Truncate: config.Truncate,
-			ResetSeq: config.ResetSeq,
-			DryRun:   config.DryRun,
+			FilePath:        config.FilePath,
+			Truncate:        config.Truncate,
+			ResetSeq:        config.ResetSeq,
+			DryRun:          config.DryRun,
+			Now:             config.Now,
+			Seed:            config.Seed,
+			CheckSchema:     !config.SkipSchemaCheck,
+			StrictGenerated: config.StrictGenerated,
+			Workers:         config.Workers,
+			Tx:              config.Tx,
+			Retry:           config.Retry,
+			Lock:            config.Lock,
 		},
 	}
 
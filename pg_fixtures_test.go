@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+
+	pgdb "github.com/rom8726/pgfixtures/internal/db"
 )
 
 type user struct {
@@ -847,3 +850,288 @@ func TestLoadPostgreSQL__complex_templates(t *testing.T) {
 		require.InEpsilon(t, expected.Price, orderProducts[i].Price, 0.0001)
 	}
 }
+
+func TestLoadPostgreSQL__identity_always(t *testing.T) {
+	connStr := runPostgres(t)
+
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	applyMigration(t, db, "testdata/migration_postgresql_generated.sql")
+
+	cfg := &Config{
+		FilePath:     "testdata/fixtures_generated.yml",
+		ConnStr:      connStr,
+		DatabaseType: PostgreSQL,
+		Truncate:     true,
+		ResetSeq:     true,
+	}
+	require.NoError(t, Load(context.Background(), cfg), "load fixtures")
+
+	// Explicit ids go in with OVERRIDING SYSTEM VALUE, the slug is computed by the database
+	rows, err := db.Query("SELECT id, slug FROM tickets ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type ticket struct {
+		ID   int
+		Slug string
+	}
+	var tickets []ticket
+	for rows.Next() {
+		var tk ticket
+		require.NoError(t, rows.Scan(&tk.ID, &tk.Slug))
+		tickets = append(tickets, tk)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []ticket{{ID: 1, Slug: "first"}, {ID: 5, Slug: "second"}}, tickets)
+
+	// The identity continues after the loaded ids
+	var id int
+	require.NoError(t, db.QueryRow("INSERT INTO tickets (title) VALUES ('Third') RETURNING id").Scan(&id))
+	require.Equal(t, 6, id)
+}
+
+func TestLoadPostgreSQL__foreign_keys(t *testing.T) {
+	connStr := runPostgres(t)
+
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	applyMigration(t, db, "testdata/migration_postgresql_foreign_keys.sql")
+
+	// Every child table sorts before its parent, so only the foreign keys put the parent first
+	load := func(t *testing.T, connStr, path string) {
+		t.Helper()
+
+		cfg := &Config{
+			FilePath:     path,
+			ConnStr:      connStr,
+			DatabaseType: PostgreSQL,
+			Truncate:     true,
+			ResetSeq:     true,
+		}
+		require.NoError(t, Load(context.Background(), cfg), "load fixtures")
+	}
+	count := func(t *testing.T, table string) int {
+		t.Helper()
+
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		return n
+	}
+
+	t.Run("composite", func(t *testing.T) {
+		keys, err := (&pgdb.PostgresDatabase{}).GetForeignKeys(context.Background(), db, []string{"public.a_transfers"})
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, "public.accounts", keys[0].RefTable)
+		require.Equal(t, []string{"account_number", "region"}, keys[0].Columns)
+		require.Equal(t, []string{"number", "region"}, keys[0].RefColumns)
+
+		load(t, connStr, "testdata/fixtures_fk_composite.yml")
+		require.Equal(t, 1, count(t, "a_transfers"))
+	})
+
+	t.Run("cross_schema", func(t *testing.T) {
+		graph, err := (&pgdb.PostgresDatabase{}).GetDependencyGraph(context.Background(), db)
+		require.NoError(t, err)
+		require.Equal(t, []string{"public.customers"}, graph["billing.invoices"])
+
+		load(t, connStr, "testdata/fixtures_fk_cross_schema.yml")
+		require.Equal(t, 1, count(t, "billing.invoices"))
+	})
+
+	t.Run("partitioned", func(t *testing.T) {
+		graph, err := (&pgdb.PostgresDatabase{}).GetDependencyGraph(context.Background(), db)
+		require.NoError(t, err)
+		require.Contains(t, graph["public.a_event_logs"], "public.events_2024")
+		require.Contains(t, graph["public.a_event_logs_2025"], "public.events")
+
+		load(t, connStr, "testdata/fixtures_fk_partitioned.yml")
+		require.Equal(t, 2, count(t, "events"))
+		require.Equal(t, 1, count(t, "a_event_logs_2024"))
+		require.Equal(t, 1, count(t, "a_event_logs_2025"))
+	})
+
+	t.Run("non_owner", func(t *testing.T) {
+		// The loading role has privileges on the tables but doesn't own them
+		loaderConnStr := strings.Replace(connStr, "user:password@", "fixtures_loader:password@", 1)
+
+		loaderDB, err := sql.Open("postgres", loaderConnStr)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = loaderDB.Close() })
+
+		keys, err := (&pgdb.PostgresDatabase{}).GetForeignKeys(context.Background(), loaderDB, []string{"shop.carts"})
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, "shop.customers", keys[0].RefTable)
+
+		load(t, loaderConnStr, "testdata/fixtures_fk_non_owner.yml")
+		require.Equal(t, 1, count(t, "shop.carts"))
+	})
+}
+
+func TestLoadPostgreSQL__reset_sequences(t *testing.T) {
+	connStr := runPostgres(t)
+
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	applyMigration(t, db, "testdata/migration_postgresql_sequences.sql")
+
+	tables := []string{"inventory.items", "public.badges", "public.countdown", "public.counters"}
+	owned, err := (&pgdb.PostgresDatabase{}).OwnedSequences(context.Background(), db, tables)
+	require.NoError(t, err)
+	require.Equal(t, []pgdb.SequenceReset{
+		{Table: "inventory.items", Column: "id", Sequence: "inventory.items_id_seq"},
+		{Table: "public.badges", Column: "id", Sequence: "badges_id_seq"},
+		{Table: "public.countdown", Column: "id", Sequence: "countdown_id_seq"},
+		{Table: "public.counters", Column: "id", Sequence: "counters_id_seq"},
+	}, owned)
+
+	// Without the cleanup, TRUNCATE ... RESTART IDENTITY doesn't touch the sequences
+	cfg := &Config{
+		FilePath:     "testdata/fixtures_sequences.yml",
+		ConnStr:      connStr,
+		DatabaseType: PostgreSQL,
+		ResetSeq:     true,
+	}
+	require.NoError(t, Load(context.Background(), cfg), "load fixtures")
+
+	next := func(table string) int {
+		t.Helper()
+
+		var id int
+		require.NoError(t, db.QueryRow("INSERT INTO "+table+" (name) VALUES ('next') RETURNING id").Scan(&id))
+		return id
+	}
+	require.Equal(t, 10, next("counters"), "empty table restarts at the start value")
+	require.Equal(t, 6, next("badges"), "identity continues after the loaded ids")
+	require.Equal(t, 989, next("countdown"), "descending sequence continues below the loaded ids")
+	require.Equal(t, 8, next("inventory.items"), "sequence in another schema")
+}
+
+func TestLoadMySQL__failed_load_keeps_rows(t *testing.T) {
+	connStr := runMySQL(t)
+
+	db, err := sql.Open("mysql", connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	applyMigration(t, db, "testdata/migration_mysql.sql")
+
+	cfg := &Config{
+		FilePath:     "testdata/fixtures_01.yml",
+		ConnStr:      connStr,
+		DatabaseType: MySQL,
+		Truncate:     true,
+		ResetSeq:     true,
+	}
+	require.NoError(t, Load(context.Background(), cfg), "load fixtures")
+
+	// The DELETE cleanup runs in the load transaction, so the failed insert rolls it back
+	cfg.FilePath = "testdata/fixtures_mysql_failing.yml"
+	require.Error(t, Load(context.Background(), cfg))
+
+	var ids []int
+	rows, err := db.Query("SELECT id FROM users ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []int{1, 2}, ids)
+
+	var orders int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&orders))
+	require.Equal(t, 2, orders)
+}
+
+// runPostgres starts a PostgreSQL container for the test and returns its connection string
+func runPostgres(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+
+	postgresContainer, err := postgres.Run(ctx,
+		"postgres:16",
+		postgres.WithDatabase("db"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(15*time.Second),
+		),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		if err := postgresContainer.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	time.Sleep(5 * time.Second)
+
+	connStr, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	return connStr
+}
+
+// applyMigration runs the statements of a migration file
+func applyMigration(t *testing.T, db *sql.DB, path string) {
+	t.Helper()
+
+	migrationSQL, err := os.ReadFile(path)
+	require.NoError(t, err, "read migrations")
+
+	_, err = db.Exec(string(migrationSQL))
+	require.NoError(t, err, "apply migrations")
+}
+
+// runMySQL starts a MySQL container for the test and returns a connection string for root
+func runMySQL(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+
+	mysqlContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "mysql:8.0",
+			ExposedPorts: []string{"3306/tcp"},
+			Env: map[string]string{
+				"MYSQL_ROOT_PASSWORD": "password",
+				"MYSQL_DATABASE":      "db",
+				"MYSQL_USER":          "user",
+				"MYSQL_PASSWORD":      "password",
+			},
+			WaitingFor: wait.ForLog("port: 3306  MySQL Community Server").
+				WithStartupTimeout(30 * time.Second),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		if err := mysqlContainer.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	time.Sleep(5 * time.Second)
+
+	host, err := mysqlContainer.Host(ctx)
+	require.NoError(t, err)
+
+	port, err := mysqlContainer.MappedPort(ctx, "3306/tcp")
+	require.NoError(t, err)
+
+	return fmt.Sprintf("root:password@tcp(%s:%s)/db?multiStatements=true&parseTime=true", host, port.Port())
+}
@@ -0,0 +1,70 @@
+-- Composite key, referenced in a different column order
+CREATE TABLE IF NOT EXISTS accounts (
+    region TEXT NOT NULL,
+    number INTEGER NOT NULL,
+    PRIMARY KEY (region, number)
+);
+
+CREATE TABLE IF NOT EXISTS a_transfers (
+    id INTEGER PRIMARY KEY,
+    account_number INTEGER NOT NULL,
+    region TEXT NOT NULL,
+    FOREIGN KEY (account_number, region) REFERENCES accounts (number, region)
+);
+
+-- Reference across schemas
+CREATE SCHEMA IF NOT EXISTS billing;
+
+CREATE TABLE IF NOT EXISTS customers (
+    id INTEGER PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS billing.invoices (
+    id INTEGER PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers (id)
+);
+
+-- Partitioned parent and partitioned child
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER NOT NULL,
+    created_on DATE NOT NULL,
+    PRIMARY KEY (id, created_on)
+) PARTITION BY RANGE (created_on);
+
+CREATE TABLE IF NOT EXISTS events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+CREATE TABLE IF NOT EXISTS events_2025 PARTITION OF events FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+
+CREATE TABLE IF NOT EXISTS a_event_logs (
+    id INTEGER NOT NULL,
+    event_id INTEGER NOT NULL,
+    event_on DATE NOT NULL,
+    logged_on DATE NOT NULL,
+    PRIMARY KEY (id, logged_on),
+    FOREIGN KEY (event_id, event_on) REFERENCES events (id, created_on)
+) PARTITION BY RANGE (logged_on);
+
+CREATE TABLE IF NOT EXISTS a_event_logs_2024 PARTITION OF a_event_logs FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+CREATE TABLE IF NOT EXISTS a_event_logs_2025 PARTITION OF a_event_logs FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+
+-- Tables owned by another role than the one loading the fixtures
+CREATE ROLE fixtures_owner;
+CREATE ROLE fixtures_loader LOGIN PASSWORD 'password';
+CREATE SCHEMA IF NOT EXISTS shop AUTHORIZATION fixtures_owner;
+
+SET ROLE fixtures_owner;
+
+-- No serial columns: TRUNCATE ... RESTART IDENTITY needs to own the sequences
+CREATE TABLE IF NOT EXISTS shop.customers (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shop.carts (
+    id INTEGER PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES shop.customers (id)
+);
+
+RESET ROLE;
+
+GRANT USAGE ON SCHEMA shop TO fixtures_loader;
+GRANT SELECT, INSERT, DELETE, TRUNCATE ON ALL TABLES IN SCHEMA shop TO fixtures_loader;
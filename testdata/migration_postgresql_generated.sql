@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS tickets (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    title TEXT NOT NULL,
+    slug TEXT GENERATED ALWAYS AS (lower(title)) STORED
+);
@@ -0,0 +1,33 @@
+-- Identity with a custom start, emptied by the fixtures
+CREATE TABLE IF NOT EXISTS counters (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY (START WITH 10) PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+-- Identity that only takes explicit values with OVERRIDING SYSTEM VALUE
+CREATE TABLE IF NOT EXISTS badges (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+-- Descending sequence attached with OWNED BY
+CREATE SEQUENCE IF NOT EXISTS countdown_id_seq INCREMENT BY -1 MINVALUE 1 MAXVALUE 1000 START WITH 1000;
+
+CREATE TABLE IF NOT EXISTS countdown (
+    id INTEGER PRIMARY KEY DEFAULT nextval('countdown_id_seq'),
+    name TEXT NOT NULL
+);
+
+ALTER SEQUENCE countdown_id_seq OWNED BY countdown.id;
+
+-- Serial column in another schema
+CREATE SCHEMA IF NOT EXISTS inventory;
+
+CREATE TABLE IF NOT EXISTS inventory.items (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+-- Move the sequence of the emptied table past its start
+INSERT INTO counters (name) VALUES ('a'), ('b'), ('c');
+DELETE FROM counters;